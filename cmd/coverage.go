@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+
+	coveragepkg "github.com/cjp2600/trep/coverage"
+	parserpkg "github.com/cjp2600/trep/parser"
+	"github.com/cjp2600/trep/source"
+)
+
+var coverProfile string
+
+// coverProfileArg returns the coverage profile path if it is already passed to go test
+func coverProfileArg(args []string) (string, bool) {
+	for i, arg := range args {
+		for _, flag := range []string{"-coverprofile", "--coverprofile", "-test.coverprofile"} {
+			if arg == flag && i+1 < len(args) {
+				return args[i+1], true
+			}
+			if strings.HasPrefix(arg, flag+"=") {
+				return strings.TrimPrefix(arg, flag+"="), true
+			}
+		}
+	}
+	return "", false
+}
+
+// prepareCoverProfile makes sure go test writes a coverage profile. It returns
+// the arguments to run go test with, the path of the profile and a function
+// removing the profile if it is a temporary file.
+func prepareCoverProfile(args []string) ([]string, string, func(), error) {
+	noop := func() {}
+
+	if filename, ok := coverProfileArg(args); ok {
+		return args, filename, noop, nil
+	}
+
+	if coverProfile != "" {
+		return append(args, "-coverprofile="+coverProfile), coverProfile, noop, nil
+	}
+
+	f, err := os.CreateTemp("", "trep-coverprofile-*.out")
+	if err != nil {
+		return nil, "", noop, fmt.Errorf("error creating coverage profile: %w", err)
+	}
+	f.Close()
+
+	return append(args, "-coverprofile="+f.Name()), f.Name(), func() { os.Remove(f.Name()) }, nil
+}
+
+// loadCoverProfile reads the coverage profile, resolves its sources and
+// attaches it to the summary. A missing or broken profile only prints a warning.
+func loadCoverProfile(sum *parserpkg.Summary, filename string) {
+	if filename == "" {
+		return
+	}
+
+	if info, err := os.Stat(filename); err != nil || info.Size() == 0 {
+		return
+	}
+
+	profile, err := coveragepkg.ReadProfile(filename)
+	if err != nil {
+		fmt.Println(textpkg.FgYellow.Sprintf("warning: %s", err.Error()))
+		return
+	}
+
+	profile.Resolve(source.NewResolver())
+	sum.Profile = profile
+}
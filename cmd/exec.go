@@ -1,22 +1,18 @@
 package cmd
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
-	"sort"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/cjp2600/trep/tui"
-	textpkg "github.com/jedib0t/go-pretty/v6/text"
 	"github.com/spf13/cobra"
 
 	parserpkg "github.com/cjp2600/trep/parser"
-	reportpkg "github.com/cjp2600/trep/report"
 )
 
 var ExecCmd = &cobra.Command{
@@ -26,21 +22,15 @@ var ExecCmd = &cobra.Command{
 	Run:   executeCommand,
 }
 
-var onlyFail bool
-var report bool
-var reportPath string
-var mode string
-var reportName string
-
 func init() {
-	ExecCmd.Flags().StringVarP(&reportName, "report-name", "n", "", "Custom report name Example: report")
-	ExecCmd.Flags().BoolVarP(&onlyFail, "only-fail", "f", false, "Only display failed tests")
-	ExecCmd.Flags().BoolVarP(&report, "report", "r", false, "Generate a report")
-	ExecCmd.Flags().StringVarP(&reportPath, "report-path", "p", "./", "Path to save the report (default is current directory)")
-	ExecCmd.Flags().StringVarP(&mode, "mode", "m", "cli", "Run mode (e.g. 'cli', 'ci')")
+	addOutputFlags(ExecCmd)
+	ExecCmd.Flags().StringVar(&coverProfile, "coverprofile", "", "Path to write the coverage profile to (default is a temporary file)")
 }
 
 type Exec struct {
+	// mu serializes the access to the parser, which is fed from stdout and
+	// stderr of go test concurrently
+	mu     sync.Mutex
 	parser parserpkg.Parser
 }
 
@@ -88,36 +78,11 @@ func executeCommand(cmd *cobra.Command, args []string) {
 	parsedArgs := parseArguments(args)
 	parsedArgsWithRequiredFlag := checkAndAddFlags(parsedArgs, "--json", "-v", "--cover")
 	if !strings.Contains(strings.Join(parsedArgsWithRequiredFlag, " "), "go test") {
-		fmt.Println(textpkg.FgRed.Sprintf("Error: exec command only supports go test commands"))
-		os.Exit(1)
-		return
-	}
-
-	if err := runCommand(parsedArgsWithRequiredFlag[0], parsedArgsWithRequiredFlag[1:]...); err != nil {
-		fmt.Println(textpkg.FgRed.Sprintf(err.Error()))
-		os.Exit(1)
+		exit(errors.New("Error: exec command only supports go test commands"))
 		return
 	}
 
-	os.Exit(0)
-}
-
-// collectNonJSONLines collects non JSON lines and returns them as a string
-func collectNonJSONLines(line string, nonJSONLines *[]string) bool {
-	if !isJSON(line) {
-		*nonJSONLines = append(*nonJSONLines, line)
-		return true
-	}
-	return false
-}
-
-// checkBuildFailure checks if there are any build failures
-func checkBuildFailure(line string) error {
-	if strings.HasPrefix(line, "FAIL") && strings.Contains(line, "[build failed]") {
-		return fmt.Errorf("error: there are build issues. please check the logs and source code")
-	}
-
-	return nil
+	exit(runCommand(parsedArgsWithRequiredFlag[0], parsedArgsWithRequiredFlag[1:]...))
 }
 
 // isJSON checks if the given string is a valid JSON
@@ -126,34 +91,6 @@ func isJSON(str string) bool {
 	return json.Unmarshal([]byte(str), &js) == nil
 }
 
-// groupActionHandler groups actions by package and executes the handler function
-func groupActionHandler(actions []*parserpkg.Action, handler func(action *parserpkg.Action)) {
-	sort.Slice(actions, func(i, j int) bool {
-		return actions[i].Time.Before(actions[j].Time)
-	})
-
-	var groupActions = make(map[string][]*parserpkg.Action)
-	for _, action := range actions {
-		groupActions[action.Package] = append(groupActions[action.Package], action)
-	}
-
-	for k, actionsList := range groupActions {
-		for _, action := range actionsList {
-			if action.Action == "skip" {
-				delete(groupActions, k)
-			}
-		}
-	}
-
-	if len(groupActions) > 0 {
-		for _, actionsList := range groupActions {
-			for _, action := range actionsList {
-				handler(action)
-			}
-		}
-	}
-}
-
 // printAction prints the given action
 func parseAction(actionStr string) (*parserpkg.Action, error) {
 	if isJSON(actionStr) {
@@ -170,6 +107,17 @@ func parseAction(actionStr string) (*parserpkg.Action, error) {
 
 // runCommand runs the given command and formats its output
 func runCommand(name string, args ...string) error {
+	// go test is always run with a coverage profile
+	if err := validateOutputFlags(true); err != nil {
+		return err
+	}
+
+	args, profilePath, removeProfile, err := prepareCoverProfile(args)
+	if err != nil {
+		return err
+	}
+	defer removeProfile()
+
 	ex := NewExec()
 
 	cmd := exec.Command(name, args...)
@@ -182,117 +130,70 @@ func runCommand(name string, args ...string) error {
 		return fmt.Errorf("error getting stderr pipe: %w", err)
 	}
 
-	scanner := bufio.NewScanner(io.MultiReader(stdout, stderr))
 	if err = cmd.Start(); err != nil {
 		return fmt.Errorf("error starting command: %w", err)
 	}
 
-	stopCh := make(chan bool)
-	{
-		loader(stopCh)
-	}
+	// stderr is read concurrently, go test would block once the pipe buffer
+	// is full while the output on stdout is still being read
+	buildOutputErr := make(chan error, 1)
+	go func() {
+		buildOutputErr <- ex.streamBuildOutput(stderr)
+	}()
 
-	var actions []*parserpkg.Action
-	var buildFailureErr error
-	nonJSONLines := []string{}
-	for scanner.Scan() {
-		line := scanner.Text()
-		if collectNonJSONLines(line, &nonJSONLines) {
-			if err = checkBuildFailure(line); err != nil {
-				buildFailureErr = err
-			}
-		}
+	stopProgress := ex.startProgress()
+	_, err = ex.stream(stdout)
+	stopProgress()
 
-		action, err := parseAction(line)
-		if err != nil {
-			continue
-		}
-		actions = append(actions, action)
+	if err != nil {
+		// go test may be blocked writing the output that is no longer read
+		_ = cmd.Process.Kill()
+		<-buildOutputErr
+		_ = cmd.Wait()
+		return err
 	}
-
-	if buildFailureErr != nil {
-		stopCh <- true
-		return fmt.Errorf("%w:\n\n %s", buildFailureErr, strings.Join(nonJSONLines, "\n"))
+	if err = <-buildOutputErr; err != nil {
+		_ = cmd.Wait()
+		return err
 	}
 
-	// Stop the loader
-	stopCh <- true
-
-	// group actions by package
-	groupActionHandler(actions, func(action *parserpkg.Action) {
-		ex.parser.Parse(action)
-	})
+	testsErr := newTestFailureError(cmd.Wait())
 
 	sum := ex.parser.GetSummary()
-	{
-		var opts []tui.RenderOptionFunc
-		if onlyFail {
-			if sum.TotalFailed > 0 {
-				opts = append(opts, tui.WithOnlyFail())
-			} else {
-				if report {
-					if err = reportpkg.GenerateAndSaveReport(sum, reportPath, reportName); err != nil {
-						return fmt.Errorf("error save report output: %w", err)
-					}
-				}
-
-				fmt.Println(textpkg.FgGreen.Sprint("All tests passed!"))
-				fmt.Println(textpkg.FgGreen.Sprint(sum.TotalPackages, " tests total, ", sum.TotalPassed, " tests passed, ", sum.TotalFailed, " tests failed"))
-				return nil
-			}
-		}
-		if mode == "ci" {
-			opts = append(opts, tui.WithEnableCIMode(true))
-		}
-
-		tui.BuildTable(sum, opts...).Render()
+	loadCoverProfile(sum, profilePath)
+	if err = renderResults(sum); err != nil {
+		return err
 	}
 
-	if report {
-		if err = reportpkg.GenerateAndSaveReport(sum, reportPath, reportName); err != nil {
-			return fmt.Errorf("error save report output: %w", err)
+	return reportOutcome(sum, testsErr)
+}
+
+// startProgress displays the progress while the tests are running and
+// returns the function that removes it. The live view redraws the terminal,
+// so it is only shown when the output is one.
+func (e *Exec) startProgress() func() {
+	if mode == CIMode {
+		fmt.Printf("Running tests...")
+		return func() {
+			fmt.Printf("\r")
 		}
 	}
-
-	if err = cmd.Wait(); err != nil {
-		fmt.Println(textpkg.FgRed.Sprint(sum.TotalPackages, " tests total, ", sum.TotalPassed, " tests passed, ", sum.TotalFailed, " tests failed"))
-		return fmt.Errorf("tests failed: %w", err)
+	if !isTerminal(os.Stdout) {
+		return func() {}
 	}
 
-	fmt.Println(textpkg.FgGreen.Sprint(sum.TotalPackages, " tests total, ", sum.TotalPassed, " tests passed, ", sum.TotalFailed, " tests failed"))
-	fmt.Println(textpkg.FgGreen.Sprint("All tests passed!"))
-	return nil
-}
-
-// loader displays a loader while the tests are running
-func loader(stopCh chan bool) {
-	go func() {
-		if mode == CIMode {
-			fmt.Printf("Running tests...")
-			for {
-				select {
-				case <-stopCh:
-					fmt.Printf("\r")
-				}
-			}
-		} else {
-			loaderChars := `-\|/`
-			i := 0
-			for {
-				select {
-				case <-stopCh:
-					fmt.Printf("\r")
-					return
-				default:
-					fmt.Printf("\r%s Running tests... ", textpkg.FgCyan.Sprintf(string(loaderChars[i%len(loaderChars)])))
-					time.Sleep(100 * time.Millisecond)
-					i++
-				}
-			}
-		}
-	}()
+	progress := tui.NewProgress(os.Stdout)
+	e.parser.Subscribe(progress.Handle)
+	progress.Start()
+	return progress.Stop
 }
 
 const (
 	CIMode = "ci"
 )
+
+// isTerminal reports whether the file is a terminal rather than a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Exit codes of trep itself. They are chosen not to clash with the codes of
+// go test, which are forwarded as is when tests fail.
+const (
+	ExitCodeBuildFailure = 10 // a package failed to build
+	ExitCodeCoverage     = 11 // the coverage is below --min-coverage
+	ExitCodeTimeout      = 12 // the tests exceeded the -timeout of go test
+	ExitCodeInternal     = 13 // trep itself failed, e.g. invalid flags or unreadable output
+)
+
+// TestFailureError is returned when go test reported failed tests
+type TestFailureError struct {
+	ExitCode int  // exit code of go test
+	Timeout  bool // the tests were aborted by the -timeout of go test
+}
+
+func (e *TestFailureError) Error() string {
+	if e.Timeout {
+		return "tests failed: test timed out"
+	}
+	return fmt.Sprintf("tests failed: exit status %d", e.ExitCode)
+}
+
+// BuildFailureError is returned when go test could not build a package
+type BuildFailureError struct {
+	Packages []string // the packages that failed to build
+}
+
+func (e *BuildFailureError) Error() string {
+	return fmt.Sprintf("build failed: %s", strings.Join(e.Packages, ", "))
+}
+
+// CoverageError is returned when the coverage is below the minimum coverage
+type CoverageError struct {
+	Minimum    float64
+	Violations []string // the packages, and the total, below the minimum with their coverage
+}
+
+func (e *CoverageError) Error() string {
+	return fmt.Sprintf("coverage is below the minimum of %.1f%%:\n  %s", e.Minimum, strings.Join(e.Violations, "\n  "))
+}
+
+// newTestFailureError returns the failure of the given go test process error,
+// or nil if it did not exit with a non-zero code
+func newTestFailureError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &TestFailureError{ExitCode: exitErr.ExitCode()}
+	}
+	if err != nil {
+		return fmt.Errorf("error waiting for command: %w", err)
+	}
+	return nil
+}
+
+// exitCode returns the code trep exits with for the given error
+func exitCode(err error) int {
+	var testErr *TestFailureError
+	var buildErr *BuildFailureError
+	var coverageErr *CoverageError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &testErr):
+		if testErr.Timeout {
+			return ExitCodeTimeout
+		}
+		if testErr.ExitCode <= 0 {
+			return 1
+		}
+		return testErr.ExitCode
+	case errors.As(err, &buildErr):
+		return ExitCodeBuildFailure
+	case errors.As(err, &coverageErr):
+		return ExitCodeCoverage
+	}
+
+	return ExitCodeInternal
+}
+
+// exit prints the given error and terminates trep with the matching exit code
+func exit(err error) {
+	if err != nil {
+		fmt.Println(textpkg.FgRed.Sprint(err.Error()))
+	}
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var ParseCmd = &cobra.Command{
+	Use:   "parse [file|-]",
+	Short: "Formats the output of a previous go test -json run read from a file or stdin",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   parseCommand,
+}
+
+func init() {
+	addOutputFlags(ParseCmd)
+	ParseCmd.Flags().StringVar(&coverProfile, "coverprofile", "", "Coverage profile of the run to include in the output")
+}
+
+// parseCommand parses the given go test -json output and formats it
+func parseCommand(cmd *cobra.Command, args []string) {
+	source := "-"
+	if len(args) > 0 {
+		source = args[0]
+	}
+
+	exit(parseOutput(source))
+}
+
+// parseOutput reads go test -json output from the given file, or from stdin
+// when the source is "-", and formats it
+func parseOutput(source string) error {
+	if err := validateOutputFlags(coverProfile != ""); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if source != "-" {
+		f, err := os.Open(source)
+		if err != nil {
+			return fmt.Errorf("error opening file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ex := NewExec()
+
+	stopProgress := ex.startProgress()
+	actions, err := ex.stream(r)
+	stopProgress()
+
+	if err != nil {
+		return err
+	}
+
+	sum := ex.parser.GetSummary()
+	// output without any action is not go test -json output, e.g. the output
+	// of go test without -json, and must not be reported as passed. Go before
+	// 1.24 prints build failures as plain text though.
+	if actions == 0 && sum.BuildFailures == 0 {
+		return fmt.Errorf("no go test -json events found in %s", sourceName(source))
+	}
+
+	loadCoverProfile(sum, coverProfile)
+	if err = renderResults(sum); err != nil {
+		return err
+	}
+
+	var testsErr error
+	if !sum.IsPassed() {
+		testsErr = &TestFailureError{ExitCode: 1}
+	}
+
+	return reportOutcome(sum, testsErr)
+}
+
+// sourceName returns the name of the source for messages
+func sourceName(source string) string {
+	if source == "-" {
+		return "stdin"
+	}
+	return source
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cjp2600/trep/tui"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+	"github.com/spf13/cobra"
+
+	configpkg "github.com/cjp2600/trep/config"
+	parserpkg "github.com/cjp2600/trep/parser"
+	reportpkg "github.com/cjp2600/trep/report"
+	"github.com/cjp2600/trep/source"
+)
+
+var onlyFail bool
+var reportPath string
+var mode string
+var reportName string
+var reportFormats []string
+var minCoverage float64
+var collapsePassed bool
+var durations bool
+var topSlow int
+var sortOrder string
+var snippets bool
+var configFile string
+
+// addOutputFlags adds the flags controlling how the results are rendered and reported
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&reportName, "report-name", "n", "", "Custom report name Example: report")
+	cmd.Flags().BoolVarP(&onlyFail, "only-fail", "f", false, "Only display failed tests")
+	cmd.Flags().StringSliceVarP(&reportFormats, "report", "r", nil, fmt.Sprintf("Generate reports in the given formats, comma separated (%s)", strings.Join(reportpkg.Formats(), ", ")))
+	// a bare -r keeps generating the HTML report, as when it was a boolean flag
+	cmd.Flags().Lookup("report").NoOptDefVal = "html"
+	cmd.Flags().StringVarP(&reportPath, "report-path", "p", "./", "Path to save the report (default is current directory)")
+	cmd.Flags().Float64Var(&minCoverage, "min-coverage", 0, "Fail when the coverage of a package or the total coverage is below the given percent. The total is weighted by statements with a coverage profile, an unweighted average of the packages otherwise")
+	cmd.Flags().BoolVar(&collapsePassed, "collapse-passed", false, "Display every passed package as a single row")
+	cmd.Flags().BoolVar(&durations, "durations", false, "Display the elapsed time of every package and test")
+	cmd.Flags().IntVar(&topSlow, "top-slow", 0, "Display the given number of slowest tests and packages after the table")
+	cmd.Flags().StringVar(&sortOrder, "sort", "", fmt.Sprintf("Order of packages and tests (%s), default is the execution order", strings.Join(parserpkg.SortOrders(), ", ")))
+	cmd.Flags().BoolVar(&snippets, "snippets", false, "Display the source around the location of every failure")
+	cmd.Flags().StringVarP(&mode, "mode", "m", "cli", "Run mode (e.g. 'cli', 'ci')")
+	cmd.Flags().StringVar(&configFile, "config", "", fmt.Sprintf("Path to the config file (default is %s if it exists)", configpkg.DefaultFile))
+}
+
+// NormalizeArgs joins the formats following -r or --report to the flag.
+// A bare -r generates the HTML report, so pflag would otherwise take the
+// formats of "-r html,junit" for an argument of the command.
+func NormalizeArgs(args []string) []string {
+	normalized := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(normalized, args[i:]...)
+		}
+		if (arg == "-r" || arg == "--report") && i+1 < len(args) && isFormatList(args[i+1]) {
+			normalized = append(normalized, arg+"="+args[i+1])
+			i++
+			continue
+		}
+		normalized = append(normalized, arg)
+	}
+	return normalized
+}
+
+// isFormatList reports whether the argument is a comma separated list of
+// registered report formats. Any other argument, such as the file of the
+// parse command, is left to the command.
+func isFormatList(arg string) bool {
+	for _, format := range strings.Split(arg, ",") {
+		if _, ok := reportpkg.Get(format); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// renderResults renders the table of the given summary and saves the report if requested
+func renderResults(sum *parserpkg.Summary) error {
+	order, err := parserpkg.ParseSortOrder(sortOrder)
+	if err != nil {
+		return err
+	}
+	sum.Sort(order)
+
+	var opts []tui.RenderOptionFunc
+	if onlyFail {
+		opts = append(opts, tui.WithOnlyFail())
+	}
+	if mode == CIMode {
+		opts = append(opts, tui.WithEnableCIMode(true))
+	}
+	if minCoverage > 0 {
+		opts = append(opts, tui.WithMinCoverage(minCoverage))
+	}
+	if collapsePassed {
+		opts = append(opts, tui.WithCollapsePassed())
+	}
+	if durations {
+		opts = append(opts, tui.WithDurations())
+	}
+
+	// with only failures requested there is nothing to show for a green run
+	if !onlyFail || !sum.IsPassed() {
+		tui.BuildTable(sum, opts...).Render()
+	}
+
+	if snippets && !sum.IsPassed() {
+		fmt.Print(tui.BuildSnippets(sum, source.NewResolver()))
+	}
+
+	if topSlow > 0 {
+		tui.BuildSlowestTestsTable(sum, topSlow).Render()
+		tui.BuildSlowestPackagesTable(sum, topSlow).Render()
+	}
+
+	if tui.HasBuildFailures(sum) {
+		tui.BuildDiagnosticsTable(sum, opts...).Render()
+	}
+
+	if tui.HasCoverage(sum) {
+		tui.BuildCoverageTable(sum, opts...).Render()
+	}
+
+	if len(reportFormats) > 0 {
+		if err := reportpkg.GenerateAndSave(sum, reportFormats, reportPath, reportName); err != nil {
+			return fmt.Errorf("error save report output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateOutputFlags checks the output flags and loads the config file
+// before any test is run. The coverage reports need a source for the
+// coverage profile.
+func validateOutputFlags(hasProfile bool) error {
+	if _, err := parserpkg.ParseSortOrder(sortOrder); err != nil {
+		return err
+	}
+	if err := reportpkg.ValidateFormats(reportFormats); err != nil {
+		return err
+	}
+	for _, format := range reportFormats {
+		if !hasProfile && reportpkg.ExportsProfile(format) {
+			return fmt.Errorf("the %s report needs the coverage profile of the run, pass it with --coverprofile", strings.TrimSpace(format))
+		}
+	}
+
+	cfg, err := configpkg.Load(configFile)
+	if err != nil {
+		return err
+	}
+	return cfg.RegisterExtractors()
+}
+
+// reportOutcome prints the summary of the run and returns an error if the
+// tests failed or the coverage is below the minimum
+func reportOutcome(sum *parserpkg.Summary, testsErr error) error {
+	if sum.BuildFailures > 0 {
+		printSummary(sum, textpkg.FgRed)
+		return newBuildFailureError(sum)
+	}
+
+	if testsErr != nil {
+		var failure *TestFailureError
+		if errors.As(testsErr, &failure) {
+			failure.Timeout = sum.TimedOut()
+		}
+		printSummary(sum, textpkg.FgRed)
+		return testsErr
+	}
+
+	if err := checkCoverage(sum); err != nil {
+		printSummary(sum, textpkg.FgRed)
+		return err
+	}
+
+	printSummary(sum, textpkg.FgGreen)
+	fmt.Println(textpkg.FgGreen.Sprint("All tests passed!"))
+	return nil
+}
+
+// checkCoverage returns an error listing the packages, and the total, whose
+// coverage is below the minimum coverage
+func checkCoverage(sum *parserpkg.Summary) error {
+	if minCoverage <= 0 {
+		return nil
+	}
+
+	var violations []string
+	for _, pkg := range sum.PackageResults {
+		if pkg.Coverage != nil && *pkg.Coverage < minCoverage {
+			violations = append(violations, fmt.Sprintf("%s: %.1f%%", pkg.PackageName, *pkg.Coverage))
+		}
+	}
+	if total, ok := sum.TotalCoverage(); ok && total < minCoverage {
+		violations = append(violations, fmt.Sprintf("total: %.1f%%", total))
+	}
+
+	if len(violations) > 0 {
+		return &CoverageError{Minimum: minCoverage, Violations: violations}
+	}
+
+	return nil
+}
+
+// newBuildFailureError returns the error listing the packages that failed to build
+func newBuildFailureError(sum *parserpkg.Summary) error {
+	var packages []string
+	for _, pkg := range sum.PackageResults {
+		if pkg.BuildFailed {
+			packages = append(packages, pkg.PackageName)
+		}
+	}
+	return &BuildFailureError{Packages: packages}
+}
+
+// printSummary prints the counters of the run with the given color
+func printSummary(sum *parserpkg.Summary, color textpkg.Color) {
+	for _, line := range tui.BuildSummary(sum) {
+		fmt.Println(color.Sprint(line))
+	}
+}
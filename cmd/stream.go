@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// maxLineSize is the longest line of go test output the pipeline accepts
+const maxLineSize = 10 * 1024 * 1024
+
+// stream reads the go test output line by line and applies every JSON action
+// to the parser as soon as it arrives, so subscribers are notified while the
+// tests are still running. The other lines are parsed as build output. It
+// returns the number of actions applied.
+func (e *Exec) stream(r io.Reader) (int, error) {
+	actions := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !isJSON(line) {
+			e.parseBuildOutput(line)
+			continue
+		}
+
+		action, err := parseAction(line)
+		if err != nil {
+			continue
+		}
+		e.mu.Lock()
+		e.parser.Parse(action)
+		e.mu.Unlock()
+		actions++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return actions, fmt.Errorf("error reading output: %w", err)
+	}
+
+	return actions, nil
+}
+
+// streamBuildOutput reads the output go test prints on stderr, which holds
+// the build errors of go versions before 1.24
+func (e *Exec) streamBuildOutput(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	for scanner.Scan() {
+		e.parseBuildOutput(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading build output: %w", err)
+	}
+	return nil
+}
+
+// parseBuildOutput applies a line of build output to the parser
+func (e *Exec) parseBuildOutput(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.parser.ParseBuildOutput(line)
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cjp2600/trep/extract"
+)
+
+// DefaultFile is the configuration file read from the working directory when
+// no other file is given
+const DefaultFile = ".trep.yaml"
+
+// Config is the content of the configuration file
+type Config struct {
+	Extractors []ExtractorRule `yaml:"extractors"`
+}
+
+// ExtractorRule is a user-defined rule extracting the failure message from
+// the output of a test with a regular expression. The groups named "message",
+// "file" and "line" hold the message and its location.
+type ExtractorRule struct {
+	Name     string `yaml:"name"`
+	Pattern  string `yaml:"pattern"`
+	Priority int    `yaml:"priority"` // rules with a higher priority are tried first
+}
+
+// Load reads the configuration file. A missing file is only an error when it
+// was given explicitly, otherwise the default configuration is returned.
+func Load(filename string) (*Config, error) {
+	explicit := filename != ""
+	if !explicit {
+		filename = DefaultFile
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	var cfg Config
+	if err = yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %w", filename, err)
+	}
+	if _, err = cfg.extractors(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", filename, err)
+	}
+	return &cfg, nil
+}
+
+// RegisterExtractors registers the extraction rules before the built-in
+// extractors, in the order of their priority and then of the file
+func (c *Config) RegisterExtractors() error {
+	extractors, err := c.extractors()
+	if err != nil {
+		return err
+	}
+
+	extract.Prepend(extractors...)
+	return nil
+}
+
+// extractors returns the extractors of the rules in the order they are tried
+func (c *Config) extractors() ([]extract.Extractor, error) {
+	for i, rule := range c.Extractors {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("extractor %d has no name", i+1)
+		}
+	}
+
+	rules := append([]ExtractorRule(nil), c.Extractors...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+
+	extractors := make([]extract.Extractor, 0, len(rules))
+	for _, rule := range rules {
+		e, err := extract.NewRegexp(rule.Name, rule.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		extractors = append(extractors, e)
+	}
+	return extractors, nil
+}
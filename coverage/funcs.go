@@ -0,0 +1,96 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// PathResolver maps the import path based file names of a profile to files on disk
+type PathResolver interface {
+	File(name string) (string, bool)
+}
+
+// Resolve locates the source of every file of the profile and computes the
+// coverage of the functions declared in it. Files whose source cannot be
+// found or parsed are left without functions.
+func (p *Profile) Resolve(resolver PathResolver) {
+	for _, f := range p.Files {
+		filename, ok := resolver.File(f.Name)
+		if !ok {
+			continue
+		}
+
+		functions, err := findFunctions(filename)
+		if err != nil {
+			continue
+		}
+
+		f.Path = filename
+		f.Functions = functions
+		for _, fn := range f.Functions {
+			fn.collect(f.Blocks)
+		}
+	}
+}
+
+// findFunctions returns the functions and methods declared in the given file
+func findFunctions(filename string) ([]*Function, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var functions []*Function
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		functions = append(functions, &Function{
+			Name:      funcName(fn),
+			StartLine: fset.Position(fn.Pos()).Line,
+			EndLine:   fset.Position(fn.End()).Line,
+		})
+	}
+
+	return functions, nil
+}
+
+// funcName returns the name of the function, prefixed with the receiver type for methods
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if index, ok := recv.(*ast.IndexExpr); ok {
+		recv = index.X
+	}
+	if index, ok := recv.(*ast.IndexListExpr); ok {
+		recv = index.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return fmt.Sprintf("%s.%s", ident.Name, fn.Name.Name)
+	}
+	return fn.Name.Name
+}
+
+// collect counts the statements of the blocks that lie within the function
+func (fn *Function) collect(blocks []Block) {
+	for _, b := range blocks {
+		if b.StartLine < fn.StartLine || b.EndLine > fn.EndLine {
+			continue
+		}
+		fn.Statements += b.NumStmt
+		if b.Count > 0 {
+			fn.Covered += b.NumStmt
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile is a parsed coverage profile as written by go test -coverprofile
+type Profile struct {
+	Mode  string
+	Files []*File
+}
+
+// File holds the coverage blocks of a single source file
+type File struct {
+	Name      string // import path based name, as written in the profile
+	Path      string // path of the source on disk, empty if it was not found
+	Blocks    []Block
+	Functions []*Function // set by Resolve when the source was found
+}
+
+// Block is a range of statements executed together
+type Block struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+// Function holds the coverage of a function declared in a file
+type Function struct {
+	Name       string
+	StartLine  int
+	EndLine    int
+	Statements int
+	Covered    int
+}
+
+// lineRe matches a block line of the profile: name.go:line.col,line.col numStmt count
+var lineRe = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+
+// ReadProfile reads the coverage profile at the given path
+func ReadProfile(filename string) (*Profile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error opening coverage profile: %w", err)
+	}
+	defer f.Close()
+
+	return ParseProfile(f)
+}
+
+// ParseProfile parses a coverage profile. Blocks reported more than once, as
+// happens with -coverpkg, are merged.
+func ParseProfile(r io.Reader) (*Profile, error) {
+	profile := &Profile{}
+	files := make(map[string]*File)
+	blocks := make(map[string]map[Block]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "mode: ") {
+			profile.Mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+
+		m := lineRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("invalid coverage profile line: %s", line)
+		}
+
+		name := m[1]
+		if _, ok := files[name]; !ok {
+			files[name] = &File{Name: name}
+			blocks[name] = make(map[Block]int)
+		}
+
+		b := Block{
+			StartLine: atoi(m[2]),
+			StartCol:  atoi(m[3]),
+			EndLine:   atoi(m[4]),
+			EndCol:    atoi(m[5]),
+			NumStmt:   atoi(m[6]),
+		}
+		count := atoi(m[7])
+
+		if i, ok := blocks[name][b]; ok {
+			existing := &files[name].Blocks[i]
+			if profile.Mode == "set" {
+				if count > existing.Count {
+					existing.Count = count
+				}
+			} else {
+				existing.Count += count
+			}
+			continue
+		}
+
+		b.Count = count
+		blocks[name][b] = len(files[name].Blocks)
+		files[name].Blocks = append(files[name].Blocks, b)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading coverage profile: %w", err)
+	}
+
+	for _, f := range files {
+		sort.Slice(f.Blocks, func(i, j int) bool {
+			bi, bj := f.Blocks[i], f.Blocks[j]
+			return bi.StartLine < bj.StartLine || bi.StartLine == bj.StartLine && bi.StartCol < bj.StartCol
+		})
+		profile.Files = append(profile.Files, f)
+	}
+	sort.Slice(profile.Files, func(i, j int) bool {
+		return profile.Files[i].Name < profile.Files[j].Name
+	})
+
+	return profile, nil
+}
+
+// Statements returns the number of statements and covered statements of the whole profile
+func (p *Profile) Statements() (total int, covered int) {
+	for _, f := range p.Files {
+		t, c := f.Statements()
+		total += t
+		covered += c
+	}
+	return total, covered
+}
+
+// PackageStatements returns the number of statements and covered statements
+// of the files of the given package
+func (p *Profile) PackageStatements(pkg string) (total int, covered int) {
+	for _, f := range p.Files {
+		if f.Package() == pkg {
+			t, c := f.Statements()
+			total += t
+			covered += c
+		}
+	}
+	return total, covered
+}
+
+// Package returns the import path of the package the file belongs to
+func (f *File) Package() string {
+	return path.Dir(f.Name)
+}
+
+// Statements returns the number of statements and covered statements of the file
+func (f *File) Statements() (total int, covered int) {
+	for _, b := range f.Blocks {
+		total += b.NumStmt
+		if b.Count > 0 {
+			covered += b.NumStmt
+		}
+	}
+	return total, covered
+}
+
+// Percent returns the covered part of the statements in percent
+func Percent(total int, covered int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) / float64(total) * 100
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// LineHits returns the execution count of every line with statements. A line
+// shared by several blocks gets the highest count among them.
+func (f *File) LineHits() map[int]int {
+	hits := make(map[int]int)
+	for _, b := range f.Blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
+		for n := b.StartLine; n <= b.EndLine; n++ {
+			if count, ok := hits[n]; !ok || b.Count > count {
+				hits[n] = b.Count
+			}
+		}
+	}
+	return hits
+}
+
+// SortedLines returns the line numbers of the given hits in ascending order
+func SortedLines(hits map[int]int) []int {
+	lines := make([]int, 0, len(hits))
+	for n := range hits {
+		lines = append(lines, n)
+	}
+	sort.Ints(lines)
+	return lines
+}
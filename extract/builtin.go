@@ -0,0 +1,130 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The built-in extractors are tried from the most specific format to the
+// least specific one: the assertion libraries print their failures through
+// t.Error and would otherwise be taken for plain messages.
+func init() {
+	Register(mustRegexp("testify", `Error:(?s)(?P<message>.*?)(\n\s*Test:)`))
+	Register(Func("go-cmp", extractCmpDiff))
+	Register(Func("gotest.tools", extractGotestTools))
+	Register(Func("gomega", extractGomega))
+	Register(mustRegexp("panic", `panic:(?s)(?P<message>.*?)(\n\sgoroutine)`))
+	Register(mustRegexp("log", `(?m)^\s*---\sLOG:(?P<message>.*)$`))
+	Register(mustRegexp("build", `FAIL\s*[^\s]+ \[build failed\](.*)`))
+	Register(Func("testing", extractTestingMessages))
+}
+
+var (
+	// locationRe matches the file:line prefix go test adds to the messages logged by a test
+	locationRe = regexp.MustCompile(`^\s*\S+\.go:\d+: ?(.*)$`)
+
+	// cmpDiffRe matches the legend of a go-cmp diff, e.g. (-want +got)
+	cmpDiffRe = regexp.MustCompile(`\(-\w+ \+\w+\)`)
+)
+
+// mustRegexp returns the extractor for the pattern, which must be valid
+func mustRegexp(name string, pattern string) Extractor {
+	e, err := NewRegexp(name, pattern)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// IsFrameLine reports whether the line is one of the === and --- lines go
+// test prints when a test starts, pauses or ends. Go versions before 1.20
+// indent the result lines of subtests by four spaces for every level.
+func IsFrameLine(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"=== ", "--- FAIL:", "--- PASS:", "--- SKIP:"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// logMessage is a message logged by a test, with the lines of a multi-line
+// message stripped of the indentation go test adds to them
+type logMessage []string
+
+func (m logMessage) String() string {
+	return strings.Join(m, "\n")
+}
+
+// logMessages returns the messages logged by the test with t.Error, t.Log and
+// similar, in the order they were logged
+func logMessages(output string) []logMessage {
+	var messages []logMessage
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			// the output is joined from lines that keep their newline
+			continue
+		}
+
+		if m := locationRe.FindStringSubmatch(line); m != nil {
+			messages = append(messages, logMessage{m[1]})
+			continue
+		}
+		// the following lines of a multi-line message are indented by eight spaces
+		if len(messages) > 0 && strings.HasPrefix(line, "        ") && !IsFrameLine(line) {
+			last := &messages[len(messages)-1]
+			*last = append(*last, line[8:])
+		}
+	}
+	return messages
+}
+
+// extractCmpDiff extracts the messages of the test that hold a go-cmp diff,
+// such as t.Errorf("mismatch (-want +got):\n%s", cmp.Diff(want, got))
+func extractCmpDiff(output string) (Result, bool) {
+	var found []string
+	for _, m := range logMessages(output) {
+		if cmpDiffRe.MatchString(m[0]) {
+			found = append(found, m.String())
+		}
+	}
+	return Result{Message: strings.Join(found, "\n")}, len(found) > 0
+}
+
+// extractGotestTools extracts the failures of the gotest.tools/assert
+// package, which are logged as "assertion failed: ..."
+func extractGotestTools(output string) (Result, bool) {
+	var found []string
+	for _, m := range logMessages(output) {
+		if strings.HasPrefix(m[0], "assertion failed:") {
+			found = append(found, strings.TrimSpace(m.String()))
+		}
+	}
+	return Result{Message: strings.Join(found, "\n")}, len(found) > 0
+}
+
+// extractGomega extracts the failures of gomega, which are logged on the
+// lines following an empty message, e.g. "Expected\n    <int>: 1\nto equal ..."
+func extractGomega(output string) (Result, bool) {
+	var found []string
+	for _, m := range logMessages(output) {
+		if m[0] == "" && len(m) > 1 && strings.HasPrefix(strings.TrimSpace(m[1]), "Expected") {
+			found = append(found, strings.TrimSpace(m[1:].String()))
+		}
+	}
+	return Result{Message: strings.Join(found, "\n")}, len(found) > 0
+}
+
+// extractTestingMessages extracts the messages logged by the test with the
+// testing package, which go test prefixes with their file:line
+func extractTestingMessages(output string) (Result, bool) {
+	var found []string
+	for _, m := range logMessages(output) {
+		if message := strings.TrimSpace(m.String()); message != "" {
+			found = append(found, message)
+		}
+	}
+	return Result{Message: strings.Join(found, "\n")}, len(found) > 0
+}
@@ -0,0 +1,190 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// Result is a failure message found in the output, with the location it was
+// reported at if the format includes it
+type Result struct {
+	Message string
+	File    string
+	Line    int
+}
+
+// Extractor recognizes a failure format, such as the one of an assertion
+// library, in the output of a test or package
+type Extractor interface {
+	// Name returns the name the extractor is registered under, e.g. "testify"
+	Name() string
+	// Extract returns the failure message found in the output, or false if
+	// the output is not in the format of the extractor
+	Extract(output string) (Result, bool)
+}
+
+// extractors holds the registered extractors in the order they are tried
+var extractors []Extractor
+
+// Register adds the extractor after the ones already registered, or replaces
+// the extractor previously registered under the same name in its place
+func Register(e Extractor) {
+	for i, registered := range extractors {
+		if registered.Name() == e.Name() {
+			extractors[i] = e
+			return
+		}
+	}
+	extractors = append(extractors, e)
+}
+
+// Prepend adds the extractors, in the given order, before the ones already
+// registered, replacing the extractors previously registered under the same names
+func Prepend(es ...Extractor) {
+	prepended := make([]Extractor, 0, len(es)+len(extractors))
+	prepended = append(prepended, es...)
+	for _, registered := range extractors {
+		replaced := false
+		for _, e := range es {
+			if registered.Name() == e.Name() {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			prepended = append(prepended, registered)
+		}
+	}
+	extractors = prepended
+}
+
+// Extract returns the failure message found by the first extractor that
+// recognizes the output, or the whole output if none does
+func Extract(output string) Result {
+	output = strings.TrimSpace(output)
+	for _, e := range extractors {
+		if result, ok := e.Extract(output); ok {
+			return result
+		}
+	}
+	return Result{Message: output}
+}
+
+// regexpExtractor extracts the failure message matched by a regular expression
+type regexpExtractor struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// NewRegexp returns an extractor for the failures matched by the pattern. The
+// message is the group named "message", or the whole match if there is none,
+// and the optional groups named "file" and "line" hold its location.
+func NewRegexp(name string, pattern string) (Extractor, error) {
+	if pattern == "" {
+		// an empty pattern matches any output with an empty message
+		return nil, fmt.Errorf("extractor %q has no pattern", name)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern of extractor %q: %w", name, err)
+	}
+
+	tree, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern of extractor %q: %w", name, err)
+	}
+	if line := findCapture(tree, "line"); line != nil && !matchesDigits(line) {
+		return nil, fmt.Errorf(`group "line" of extractor %q must only match digits, e.g. (?P<line>\d+)`, name)
+	}
+
+	return regexpExtractor{name: name, re: re}, nil
+}
+
+// findCapture returns the group with the given name, nil if there is none
+func findCapture(re *syntax.Regexp, name string) *syntax.Regexp {
+	if re.Op == syntax.OpCapture && re.Name == name {
+		return re
+	}
+	for _, sub := range re.Sub {
+		if found := findCapture(sub, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// matchesDigits reports whether the expression only matches decimal digits
+func matchesDigits(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if r < '0' || r > '9' {
+				return false
+			}
+		}
+		return true
+	case syntax.OpCharClass:
+		// the runes are the bounds of the ranges of the class
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if re.Rune[i] < '0' || re.Rune[i+1] > '9' {
+				return false
+			}
+		}
+		return len(re.Rune) > 0
+	case syntax.OpCapture, syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat, syntax.OpConcat, syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !matchesDigits(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (e regexpExtractor) Name() string {
+	return e.name
+}
+
+func (e regexpExtractor) Extract(output string) (Result, bool) {
+	m := e.re.FindStringSubmatch(output)
+	if m == nil {
+		return Result{}, false
+	}
+
+	message := m[0]
+	if i := e.re.SubexpIndex("message"); i >= 0 {
+		message = m[i]
+	}
+	result := Result{Message: strings.TrimSpace(strings.ReplaceAll(message, "\t", " "))}
+	if i := e.re.SubexpIndex("file"); i >= 0 {
+		result.File = m[i]
+	}
+	if i := e.re.SubexpIndex("line"); i >= 0 {
+		result.Line, _ = strconv.Atoi(m[i])
+	}
+	return result, true
+}
+
+// Func adapts a function to an extractor registered under the given name
+func Func(name string, fn func(output string) (Result, bool)) Extractor {
+	return funcExtractor{name: name, fn: fn}
+}
+
+// funcExtractor is an extractor implemented by a function
+type funcExtractor struct {
+	name string
+	fn   func(output string) (Result, bool)
+}
+
+func (e funcExtractor) Name() string {
+	return e.name
+}
+
+func (e funcExtractor) Extract(output string) (Result, bool) {
+	return e.fn(output)
+}
@@ -0,0 +1,138 @@
+package extract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The testdata files hold the output of failed tests captured from go test
+// -json. The output of every assertion library is also made of file:line
+// messages, so the order of the extractors decides which one wins.
+// go-cmp randomly uses non-breaking spaces in its diffs to keep them from
+// being parsed, they are kept as printed.
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			name: "go-cmp diff",
+			file: "go-cmp.txt",
+			want: "user mismatch (-want +got):\n" +
+				"\u00a0\u00a0libs.user{\n" +
+				"\u00a0\u00a0\tName: \"alice\",\n" +
+				"-\u00a0\tAge:  30,\n" +
+				"+\u00a0\tAge:  31,\n" +
+				"\u00a0\u00a0}",
+		},
+		{
+			name: "gotest.tools assert.Equal",
+			file: "gotest.tools.txt",
+			want: "assertion failed: 2 (1 + 1 int) != 3 (int)",
+		},
+		{
+			name: "gotest.tools assert.DeepEqual",
+			file: "gotest.tools_deepequal.txt",
+			want: "assertion failed: \n" +
+				"--- ←\n" +
+				"+++ →\n" +
+				"\u00a0\u00a0libs.user{\n" +
+				"\u00a0\u00a0\tName: \"alice\",\n" +
+				"-\u00a0\tAge:  30,\n" +
+				"+\u00a0\tAge:  31,\n" +
+				"\u00a0\u00a0}",
+		},
+		{
+			name: "gomega",
+			file: "gomega.txt",
+			want: "Expected\n    <int>: 2\nto equal\n    <int>: 3",
+		},
+		{
+			name: "testing messages",
+			file: "testing.txt",
+			want: "starting\ngot 2, want 3",
+		},
+		{
+			name: "testing message of a nested subtest",
+			file: "testing_nested_go119.txt",
+			want: "nested failure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := readOutput(t, tt.file)
+			assert.Equal(t, Result{Message: tt.want}, Extract(output))
+			// the table joins the output lines, which keep their newline
+			assert.Equal(t, Result{Message: tt.want}, Extract(strings.Join(strings.SplitAfter(output, "\n"), "\n")))
+		})
+	}
+}
+
+func TestExtractTestify(t *testing.T) {
+	result := Extract(readOutput(t, "testify.txt"))
+
+	assert.True(t, strings.HasPrefix(result.Message, "Not equal:"), result.Message)
+	assert.Contains(t, result.Message, `expected: "want"`)
+	assert.Contains(t, result.Message, `actual  : "got"`)
+	assert.NotContains(t, result.Message, "Error Trace")
+}
+
+func TestExtractWithoutKnownFormat(t *testing.T) {
+	assert.Equal(t, Result{Message: "something went wrong"}, Extract("  something went wrong\n"))
+}
+
+func TestPrependedExtractorWins(t *testing.T) {
+	registered := extractors
+	defer func() { extractors = registered }()
+
+	rule, err := NewRegexp("testify", `Error Trace:\s*(?P<file>\S+\.go):(?P<line>\d+)\s+Error:\s*(?P<message>[^\n]*)`)
+	require.NoError(t, err)
+	Prepend(rule)
+
+	assert.Equal(t, Result{Message: "Not equal:", File: "/tmp/libs/x_test.go", Line: 35}, Extract(readOutput(t, "testify.txt")))
+	// the built-in extractor of the same name is replaced, the others are kept
+	assert.Len(t, extractors, len(registered))
+	assert.Equal(t, Result{Message: "Expected\n    <int>: 2\nto equal\n    <int>: 3"}, Extract(readOutput(t, "gomega.txt")))
+}
+
+func TestNewRegexp(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		wantErr bool
+	}{
+		{name: "message only", pattern: `FAILURE: (?P<message>.*)`},
+		{name: "numeric line", pattern: `at (?P<file>\S+):(?P<line>\d+)`},
+		{name: "line class", pattern: `at (?P<file>\S+):(?P<line>[0-9]{1,6})`},
+		{name: "empty pattern", pattern: "", wantErr: true},
+		{name: "invalid pattern", pattern: `(`, wantErr: true},
+		{name: "line not numeric", pattern: `at (?P<file>\S+):(?P<line>\S+)`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegexp("rule", tt.pattern)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// readOutput returns the captured output in the testdata file
+func readOutput(t *testing.T, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(content)
+}
@@ -16,9 +16,11 @@ var rootCmd = &cobra.Command{
 
 func main() {
 	rootCmd.AddCommand(cmd.ExecCmd)
+	rootCmd.AddCommand(cmd.ParseCmd)
 
+	rootCmd.SetArgs(cmd.NormalizeArgs(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCodeInternal)
 	}
 }
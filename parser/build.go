@@ -0,0 +1,230 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is an error reported by the compiler, or by go vet, while
+// building a package. Errors without a location, such as a missing package,
+// an import cycle or an invalid go.mod, have no file.
+type Diagnostic struct {
+	File    string // empty if not reported
+	Line    int
+	Column  int // 0 if not reported
+	Message string
+}
+
+// Location returns the file:line:column of the diagnostic, or an empty
+// string if it has no location
+func (d Diagnostic) Location() string {
+	if d.File == "" {
+		return ""
+	}
+	if d.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+func (d Diagnostic) String() string {
+	if d.File == "" {
+		return d.Message
+	}
+	return d.Location() + ": " + d.Message
+}
+
+var (
+	// buildHeaderRe matches the "# package" line go prints before the errors
+	// of a package, optionally followed by the test binary in brackets
+	buildHeaderRe = regexp.MustCompile(`^# (\S+)(?: \[.*\])?$`)
+
+	// diagnosticRe matches a file:line[:column]: message line of the compiler
+	diagnosticRe = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+	// buildFailedRe matches the line go test prints for a package that could not be built
+	buildFailedRe = regexp.MustCompile(`^FAIL\s+(\S+) \[(?:build|setup) failed\]$`)
+)
+
+// ParseBuildOutput parses a line of the build output that go versions before
+// 1.24 print outside of the JSON stream. The output is kept by package and
+// attributed to it once the build is known to have failed, go also prints
+// warnings, such as the ones of the linker, under a package header.
+func (p *parser) ParseBuildOutput(line string) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if m := buildHeaderRe.FindStringSubmatch(line); m != nil {
+		p.buildPackage = m[1]
+		return
+	}
+
+	if m := buildFailedRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+		build := p.textBuild(m[1])
+		build.failed = true
+		// errors such as a missing package directory are printed without a header
+		for _, pending := range p.pendingBuild {
+			build.output = append(build.output, pending+"\n")
+			build.diagnostics = parseDiagnostic(build.diagnostics, pending)
+		}
+		p.pendingBuild = nil
+		p.buildPackage = ""
+		return
+	}
+
+	if strings.TrimSpace(line) == "" {
+		p.buildPackage = ""
+		return
+	}
+
+	if p.buildPackage == "" {
+		p.pendingBuild = append(p.pendingBuild, line)
+		return
+	}
+
+	build := p.textBuild(p.buildPackage)
+	build.output = append(build.output, line+"\n")
+	build.diagnostics = parseDiagnostic(build.diagnostics, line)
+	if diagnosticRe.MatchString(line) {
+		build.failed = true
+	}
+}
+
+// textBuild returns the build output printed as text for the package
+func (p *parser) textBuild(pkg string) *buildOutput {
+	build, ok := p.textBuilds[pkg]
+	if !ok {
+		build = &buildOutput{}
+		p.textBuilds[pkg] = build
+	}
+	return build
+}
+
+// buildOutput holds the output of a build reported by build-output actions
+type buildOutput struct {
+	output      []string
+	diagnostics []Diagnostic
+
+	// failed is set once the build output printed as text holds an error
+	failed bool
+}
+
+// parseBuildAction handles the build-output and build-fail actions that go
+// 1.24 and later emit for every build, identified by its import path
+func (p *parser) parseBuildAction(action *Action) {
+	if action.Action != "build-output" {
+		return
+	}
+
+	// go repeats the output of a failed dependency for every package that
+	// imports it, each time starting with the header
+	line := strings.TrimRight(action.Output, "\r\n")
+	if buildHeaderRe.MatchString(line) {
+		p.builds[action.ImportPath] = &buildOutput{}
+		return
+	}
+
+	build, ok := p.builds[action.ImportPath]
+	if !ok {
+		build = &buildOutput{}
+		p.builds[action.ImportPath] = build
+	}
+	build.output = append(build.output, action.Output)
+	build.diagnostics = parseDiagnostic(build.diagnostics, line)
+}
+
+// setFailedBuild marks the package as failed to build because of the build
+// with the given import path, which is either the package itself or one of
+// its dependencies
+func (p *parser) setFailedBuild(pkg *PackageResult, importPath string) {
+	p.setBuildFailed(pkg)
+	if build, ok := p.builds[importPath]; ok {
+		addBuildOutput(pkg, build)
+	}
+}
+
+// setFailedTextBuild marks the package as failed to build if its build
+// output printed as text holds an error
+func (p *parser) setFailedTextBuild(pkg *PackageResult) {
+	build, ok := p.textBuilds[pkg.PackageName]
+	if !ok || !build.failed {
+		return
+	}
+
+	p.setBuildFailed(pkg)
+	addBuildOutput(pkg, build)
+	delete(p.textBuilds, pkg.PackageName)
+}
+
+// addBuildOutput adds the output and the diagnostics of the build to the package
+func addBuildOutput(pkg *PackageResult, build *buildOutput) {
+	pkg.Output = append(pkg.Output, build.output...)
+	pkg.Diagnostics = append(pkg.Diagnostics, build.diagnostics...)
+}
+
+// parseDiagnostic adds the diagnostic of the given build output line to the
+// diagnostics, or appends the line to the previous diagnostic if it continues
+// its message. A line without a location is kept as a diagnostic without a file.
+func parseDiagnostic(diagnostics []Diagnostic, line string) []Diagnostic {
+	if m := diagnosticRe.FindStringSubmatch(line); m != nil {
+		lineNum, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+		return append(diagnostics, Diagnostic{
+			File:    m[1],
+			Line:    lineNum,
+			Column:  column,
+			Message: m[4],
+		})
+	}
+
+	// an indented line continues the message of the previous diagnostic
+	if strings.HasPrefix(line, "\t") && len(diagnostics) > 0 {
+		last := &diagnostics[len(diagnostics)-1]
+		last.Message += "\n" + strings.TrimSpace(line)
+		return diagnostics
+	}
+
+	if line = strings.TrimSpace(line); line != "" {
+		diagnostics = append(diagnostics, Diagnostic{Message: line})
+	}
+	return diagnostics
+}
+
+// setBuildFailed marks the package as failed to build
+func (p *parser) setBuildFailed(pkg *PackageResult) {
+	pkg.BuildFailed = true
+	pkg.IsPassed = false
+}
+
+// finishBuildFailures adds the packages that failed to build, and for which
+// go test reported no result, to the summary
+func (p *parser) finishBuildFailures() {
+	// stderr is read concurrently with stdout, the build errors of a package
+	// may only arrive after go test reported it
+	for _, pkg := range p.sum.PackageResults {
+		if build, ok := p.textBuilds[pkg.PackageName]; ok && build.failed && !pkg.BuildFailed {
+			p.countPackage(pkg, -1)
+			p.setFailedTextBuild(pkg)
+			p.countPackage(pkg, 1)
+		}
+	}
+	for name, build := range p.textBuilds {
+		if build.failed {
+			p.setFailedTextBuild(p.getPackage(name))
+		}
+	}
+
+	var names []string
+	for name, pkg := range p.packages {
+		if pkg.BuildFailed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p.finishPackage(p.packages[name], false)
+	}
+}
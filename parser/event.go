@@ -0,0 +1,37 @@
+package parser
+
+// EventType describes how an action changed the summary
+type EventType int
+
+const (
+	// EventPackageStart is emitted when a package starts running
+	EventPackageStart EventType = iota
+	// EventPackageOutput is emitted when a package prints output outside of a test
+	EventPackageOutput
+	// EventPackageSkip is emitted when a package has no test files and is dropped
+	EventPackageSkip
+	// EventPackageEnd is emitted when a package finished and was added to the summary
+	EventPackageEnd
+	// EventTestRun is emitted when a test or subtest starts running
+	EventTestRun
+	// EventTestOutput is emitted when a test prints output
+	EventTestOutput
+	// EventTestEnd is emitted when a test or subtest passed, failed or was skipped
+	EventTestEnd
+	// EventTestPause is emitted when a parallel test pauses until the tests that are not parallel finished
+	EventTestPause
+	// EventTestCont is emitted when a paused parallel test continues running
+	EventTestCont
+)
+
+// Event is delivered to subscribers after an action has been applied to the summary
+type Event struct {
+	Type    EventType
+	Action  *Action
+	Package *PackageResult
+	Test    *TestResult // nil for package events
+	Summary *Summary
+}
+
+// EventHandler receives the events produced by the parser
+type EventHandler func(event *Event)
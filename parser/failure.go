@@ -0,0 +1,203 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cjp2600/trep/extract"
+)
+
+// FailureKind is the way a test reported a failure
+type FailureKind string
+
+const (
+	FailureAssertion FailureKind = "assertion" // a failed testify assertion
+	FailureError     FailureKind = "error"     // the last message logged by the test, e.g. with t.Error or t.Fatal
+	FailurePanic     FailureKind = "panic"     // a panic or a timeout
+)
+
+// Failure is a failure reported by a test with the location it was reported at
+type Failure struct {
+	File    string // as printed by go test, usually relative to the package directory
+	Line    int
+	Message string
+	Kind    FailureKind
+
+	// Expected and Actual are the values compared by an assertion, and Diff
+	// the lines of the unified diff between them, exactly as printed
+	Expected string
+	Actual   string
+	Diff     []string
+}
+
+// Location returns the file:line of the failure
+func (f Failure) Location() string {
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+var (
+	// logLocationRe matches the file:line prefix of the messages logged by a test
+	logLocationRe = regexp.MustCompile(`^\s*(\S+\.go):(\d+): ?(.*)$`)
+
+	// testifyFieldRe matches a line of a testify failure block, with the label
+	// of the field, or no label if the line continues the previous field
+	testifyFieldRe = regexp.MustCompile(`^\s*\t([^\t]*)\t(.*)$`)
+
+	// traceLocationRe matches a file:line entry of a testify Error Trace
+	traceLocationRe = regexp.MustCompile(`^\s*(\S+\.go):(\d+)\s*$`)
+)
+
+// parseFailures returns the failures reported in the output of a failed
+// test. go test prints the messages of t.Log and t.Error alike, so a plain
+// message is only taken for the failure when it is the last one logged and
+// the failure has no other explanation: an assertion, a panic or a failed
+// subtest.
+func parseFailures(output []string, p *Panic, failedSubtests bool) []Failure {
+	var failures []Failure
+	var lastMessage *Failure
+
+	lines := make([]string, 0, len(output))
+	for _, line := range output {
+		lines = append(lines, strings.TrimRight(line, "\r\n"))
+	}
+
+	for i := 0; i < len(lines); i++ {
+		m := logLocationRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		lineNum, _ := strconv.Atoi(m[2])
+		failure := Failure{File: m[1], Line: lineNum, Message: m[3], Kind: FailureError}
+
+		// testify prints an empty message followed by the fields of the failure
+		if m[3] == "" && i+1 < len(lines) && testifyFieldRe.MatchString(lines[i+1]) {
+			n := parseTestifyFailure(lines[i+1:], &failure)
+			i += n
+			failures = append(failures, failure)
+			continue
+		}
+
+		// the following lines of a multi-line message are indented further
+		for i+1 < len(lines) && isContinuation(lines[i+1]) {
+			i++
+			failure.Message += "\n" + strings.TrimSpace(lines[i])
+		}
+		lastMessage = &failure
+	}
+
+	if lastMessage != nil && len(failures) == 0 && p == nil && !failedSubtests {
+		failures = append(failures, *lastMessage)
+	}
+
+	if p != nil {
+		failure := Failure{Message: p.Message, Kind: FailurePanic}
+		for _, frame := range p.Stack {
+			if frame.User {
+				failure.File = frame.File
+				failure.Line = frame.Line
+				break
+			}
+		}
+		failures = append(failures, failure)
+	}
+
+	return failures
+}
+
+// parseTestifyFailure fills the failure from the fields of a testify failure
+// block and returns the number of lines the block spans. The message keeps
+// the whitespace of the values exactly as printed.
+func parseTestifyFailure(lines []string, failure *Failure) int {
+	failure.Kind = FailureAssertion
+
+	var label string
+	var trace, errorLines, messages []string
+	n := 0
+	for _, line := range lines {
+		m := testifyFieldRe.FindStringSubmatch(line)
+		if m == nil {
+			break
+		}
+		n++
+
+		if l := strings.TrimSpace(m[1]); l != "" {
+			label = strings.TrimSuffix(l, ":")
+		}
+		switch label {
+		case "Error Trace":
+			trace = append(trace, m[2])
+		case "Error":
+			errorLines = append(errorLines, m[2])
+		case "Messages":
+			messages = append(messages, m[2])
+		}
+	}
+
+	// the first entry of the trace is the innermost call of the assertion
+	if len(trace) > 0 {
+		if m := traceLocationRe.FindStringSubmatch(trace[0]); m != nil {
+			failure.File = m[1]
+			failure.Line, _ = strconv.Atoi(m[2])
+		}
+	}
+
+	parseTestifyDiff(errorLines, failure)
+	failure.Message = strings.TrimRight(strings.Join(errorLines, "\n"), " \n")
+	if len(messages) > 0 {
+		failure.Message += "\n" + strings.Join(messages, "\n")
+	}
+	return n
+}
+
+// parseTestifyDiff fills the expected and actual values and the diff of the
+// failure from the lines of the Error field of a testify failure
+func parseTestifyDiff(lines []string, failure *Failure) {
+	var expected, actual []string
+	var field *[]string
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "expected: "):
+			expected = []string{strings.TrimPrefix(line, "expected: ")}
+			field = &expected
+		case strings.HasPrefix(line, "actual  : "):
+			actual = []string{strings.TrimPrefix(line, "actual  : ")}
+			field = &actual
+		case line == "Diff:":
+			// the diff starts with the --- Expected and +++ Actual headers
+			diff := lines[i+1:]
+			if len(diff) >= 2 && strings.HasPrefix(diff[0], "--- ") && strings.HasPrefix(diff[1], "+++ ") {
+				diff = diff[2:]
+			}
+			failure.Diff = append([]string(nil), diff...)
+			failure.Expected = strings.Join(expected, "\n")
+			failure.Actual = strings.Join(actual, "\n")
+			return
+		case field != nil && line != "":
+			*field = append(*field, line)
+		default:
+			field = nil
+		}
+	}
+
+	failure.Expected = strings.Join(expected, "\n")
+	failure.Actual = strings.Join(actual, "\n")
+}
+
+// hasFailedSubtest reports whether any subtest of the test failed
+func hasFailedSubtest(test *TestResult) bool {
+	for _, subtest := range test.Subtests {
+		if subtest.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// isContinuation reports whether the line continues a logged message, which
+// go test indents by more than the four spaces of the message itself, as it
+// does the result line of a nested subtest
+func isContinuation(line string) bool {
+	return strings.HasPrefix(line, "        ") && !logLocationRe.MatchString(line) && !extract.IsFrameLine(line)
+}
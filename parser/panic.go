@@ -0,0 +1,252 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Panic is a panic, or a -timeout of go test, that aborted a test binary
+type Panic struct {
+	Message string  // the value passed to panic, e.g. "runtime error: index out of range"
+	Timeout bool    // the panic was raised because the tests exceeded the -timeout
+	Stack   []Frame // the trimmed stack of the goroutine running the test
+}
+
+// Frame is a function call of a goroutine stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+	User     bool // the function belongs to the tested code rather than to the standard library
+}
+
+var (
+	panicRe         = regexp.MustCompile(`^panic: (.*)$`)
+	timeoutRe       = regexp.MustCompile(`^test timed out after \S+`)
+	runningTestRe   = regexp.MustCompile(`^\t\t(\S+) \(.*\)$`)
+	goroutineRe     = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	frameCallRe     = regexp.MustCompile(`^(.*)\(.*\)$`)
+	frameLocationRe = regexp.MustCompile(`^\t(.+):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// hasPanic reports whether the output contains a panic
+func hasPanic(output []string) bool {
+	for _, line := range output {
+		if panicRe.MatchString(strings.TrimRight(line, "\r\n")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePanic returns the panic printed in the output, with the stack of the
+// goroutine that ran the given test, or nil if the output has no panic
+func parsePanic(output []string, testName string) *Panic {
+	var p *Panic
+	var goroutines [][]Frame
+	var runningTests []string
+	var frames []Frame
+	inRunningTests := false
+	createdBy := false
+
+	for _, line := range output {
+		line = strings.TrimRight(line, "\r\n")
+
+		if p == nil {
+			if m := panicRe.FindStringSubmatch(line); m != nil {
+				p = &Panic{Message: m[1], Timeout: timeoutRe.MatchString(m[1])}
+			}
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "running tests:":
+			inRunningTests = true
+		case inRunningTests && runningTestRe.MatchString(line):
+			runningTests = append(runningTests, runningTestRe.FindStringSubmatch(line)[1])
+		case goroutineRe.MatchString(line):
+			inRunningTests = false
+			if frames != nil {
+				goroutines = append(goroutines, frames)
+			}
+			frames = []Frame{}
+		case frames == nil:
+			// the lines between the panic and the first goroutine
+		case strings.HasPrefix(line, "created by "):
+			createdBy = true
+		case frameLocationRe.MatchString(line):
+			if createdBy {
+				createdBy = false
+				continue
+			}
+			if len(frames) > 0 {
+				m := frameLocationRe.FindStringSubmatch(line)
+				last := &frames[len(frames)-1]
+				last.File = m[1]
+				last.Line, _ = strconv.Atoi(m[2])
+				last.User = isUserFrame(last.Function, last.File)
+			}
+		case frameCallRe.MatchString(line):
+			frames = append(frames, Frame{Function: frameCallRe.FindStringSubmatch(line)[1]})
+		}
+	}
+
+	if p == nil {
+		return nil
+	}
+	if frames != nil {
+		goroutines = append(goroutines, frames)
+	}
+
+	if p.Timeout && len(runningTests) > 0 && testName == "" {
+		testName = runningTests[len(runningTests)-1]
+	}
+	p.Stack = trimStack(selectGoroutine(goroutines, testName, p.Timeout))
+	return p
+}
+
+// failRunningTests fails the tests of the package that were still running
+// when the test binary exited, which happens when a test panics or the tests
+// time out, and attributes the panic to the innermost of them. go test
+// reports no result for these tests, so the parser emits their end itself.
+func (p *parser) failRunningTests(pkg *PackageResult, action *Action) {
+	var running []string
+	var collect func(fullName string, test *TestResult)
+	collect = func(fullName string, test *TestResult) {
+		if _, ok := p.running[testKey{pkg: pkg.PackageName, test: fullName}]; ok {
+			running = append(running, fullName)
+		}
+		for _, subtest := range test.Subtests {
+			collect(fullName+"/"+subtest.TestName, subtest)
+		}
+	}
+	for _, test := range pkg.TestResults {
+		collect(test.TestName, test)
+	}
+
+	// older go versions print the panic to the package output, the later ones
+	// to the output of the last running test
+	output := pkg.Output
+	for i := len(running) - 1; i >= 0 && !hasPanic(output); i-- {
+		output = p.running[testKey{pkg: pkg.PackageName, test: running[i]}].Output
+	}
+
+	if len(running) == 0 {
+		if hasPanic(output) {
+			pkg.Panic = parsePanic(output, "")
+		}
+		return
+	}
+
+	for _, name := range running {
+		key := testKey{pkg: pkg.PackageName, test: name}
+		test := p.running[key]
+		delete(p.running, key)
+
+		test.EndTime = action.Time
+		test.ElapsedTime = action.Time.Sub(test.StartTime).Seconds()
+		test.Status = StatusFail
+		p.testCounts(name).add(test.Status)
+
+		isInnermost := true
+		for _, other := range running {
+			if strings.HasPrefix(other, name+"/") {
+				isInnermost = false
+				break
+			}
+		}
+		if isInnermost && hasPanic(output) {
+			test.Panic = parsePanic(output, name)
+		}
+		// the running subtests are failed with it
+		test.Failures = parseFailures(test.Output, test.Panic, !isInnermost || hasFailedSubtest(test))
+
+		p.emit(&Event{
+			Type:    EventTestEnd,
+			Action:  &Action{Time: action.Time, Action: "fail", Package: pkg.PackageName, Test: name, Elapsed: test.ElapsedTime},
+			Package: pkg,
+			Test:    test,
+		})
+	}
+}
+
+// selectGoroutine returns the stack of the goroutine that ran the given test.
+// The first goroutine is the one that panicked, but on a timeout it is the
+// goroutine of the alarm, so the goroutine running the test function is
+// looked up instead.
+func selectGoroutine(goroutines [][]Frame, testName string, timeout bool) []Frame {
+	if len(goroutines) == 0 {
+		return nil
+	}
+	if !timeout || testName == "" {
+		return goroutines[0]
+	}
+
+	testFunc := strings.Split(testName, "/")[0]
+	var found []Frame
+	for _, frames := range goroutines {
+		for _, frame := range frames {
+			if isTestFunction(frame.Function, testFunc) {
+				found = frames
+				break
+			}
+		}
+		// the goroutine of a parent test is blocked in t.Run of its subtests,
+		// keep looking for the innermost one
+		if found != nil && found[0].Function != "testing.(*T).Run" {
+			return found
+		}
+	}
+	if found != nil {
+		return found
+	}
+	return goroutines[0]
+}
+
+// isTestFunction reports whether the function is the given test function or
+// one of its closures
+func isTestFunction(function string, testFunc string) bool {
+	name := function[strings.LastIndex(function, "/")+1:]
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name == testFunc || strings.HasPrefix(name, testFunc+".")
+}
+
+// trimStack drops the frames of the panic machinery that precede the function that panicked
+func trimStack(frames []Frame) []Frame {
+	for i, frame := range frames {
+		if frame.Function == "panic" || frame.Function == "runtime.gopanic" {
+			return frames[i+1:]
+		}
+	}
+	return frames
+}
+
+// isUserFrame reports whether the function is outside of the standard
+// library, whose packages have no dot in their first path element and are
+// located under $GOROOT/src
+func isUserFrame(function string, file string) bool {
+	if strings.HasSuffix(file, "_testmain.go") {
+		return false
+	}
+
+	pkg := function
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if j := strings.Index(pkg[i:], "."); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	} else if j := strings.Index(pkg, "."); j >= 0 {
+		pkg = pkg[:j]
+	} else {
+		// builtins such as panic
+		return false
+	}
+
+	if strings.Contains(strings.Split(pkg, "/")[0], ".") {
+		return true
+	}
+	// the runtime implements functions of other standard packages, e.g. time.Sleep
+	return !strings.Contains(file, "/src/"+pkg+"/") && !strings.Contains(file, "/src/runtime/")
+}
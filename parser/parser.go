@@ -1,178 +1,365 @@
 package parser
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	coveragepkg "github.com/cjp2600/trep/coverage"
 )
 
 type Parser interface {
 	Parse(action *Action)
+	ParseBuildOutput(line string)
+	Subscribe(handler EventHandler)
 	GetSummary() *Summary
 }
 
 type parser struct {
-	line string
-	sum  *Summary
+	sum      *Summary
+	handlers []EventHandler
+
+	// packages and tests hold the results that are still in progress, keyed
+	// by the identifiers carried on every action, so that interleaved events
+	// from parallel tests and packages are attributed correctly.
+	packages map[string]*PackageResult
+	tests    map[testKey]*TestResult
+
+	// buildPackage is the package whose build output is being read, and
+	// pendingBuild the build output read outside of any package header
+	buildPackage string
+	pendingBuild []string
 
-	currentPackage *PackageResult
-	currentTest    *TestResult
+	// builds holds the output of the builds, keyed by their import path, and
+	// textBuilds the build output printed as text, keyed by package
+	builds     map[string]*buildOutput
+	textBuilds map[string]*buildOutput
+
+	// running holds the tests that started and have not finished yet
+	running map[testKey]*TestResult
+}
+
+// testKey identifies a test by its package and full (slash separated) name
+type testKey struct {
+	pkg  string
+	test string
 }
 
 func NewParser() Parser {
 	return &parser{
-		sum:            &Summary{},
-		currentPackage: &PackageResult{},
-		currentTest:    &TestResult{},
+		sum:        &Summary{},
+		packages:   make(map[string]*PackageResult),
+		tests:      make(map[testKey]*TestResult),
+		builds:     make(map[string]*buildOutput),
+		textBuilds: make(map[string]*buildOutput),
+		running:    make(map[testKey]*TestResult),
 	}
 }
 
+// Subscribe registers a handler that receives an event for every action applied to the summary
+func (p *parser) Subscribe(handler EventHandler) {
+	p.handlers = append(p.handlers, handler)
+}
+
 // Parse parses the action and updates the summary
 func (p *parser) Parse(action *Action) {
 	if action == nil {
 		return
 	}
 
-	switch action.Action {
-	case "start":
-		p.currentPackage = &PackageResult{
-			PackageName: action.Package,
-			StartTime:   action.Time,
-			TestResults: make(map[string]*TestResult), // Initialize the map
-		}
-	case "output":
-		if action.Test == "" {
-			p.currentPackage.Output = append(p.currentPackage.Output, action.Output)
-			return
-		}
+	if action.ImportPath != "" && action.Package == "" {
+		p.parseBuildAction(action)
+		return
+	}
 
-		p.currentTest.Output = append(p.currentTest.Output, action.Output)
+	var event *Event
+	if action.Test == "" {
+		event = p.parsePackageAction(action)
+	} else {
+		event = p.parseTestAction(action)
+	}
 
-	case "run":
-		testNames := strings.Split(action.Test, "/")
-		testName := testNames[len(testNames)-1]
-		parentTestName := ""
-		if len(testNames) > 1 {
-			parentTestName = strings.Join(testNames[:len(testNames)-1], "/")
-		}
+	if event == nil {
+		return
+	}
 
-		p.currentTest = &TestResult{
-			TestName:  testName,
-			StartTime: action.Time,
-			IsPassed:  true,
-		}
-		p.sum.TotalPassed++
-		p.sum.TotalPackages++
+	event.Action = action
+	p.emit(event)
+}
 
-		if parentTestName == "" {
-			p.currentPackage.TestResults[action.Test] = p.currentTest
-		} else {
-			parentTest := findTest(parentTestName, p.currentPackage.TestResults)
-			if parentTest != nil {
-				parentTest.Subtests = append(parentTest.Subtests, p.currentTest)
-			} else {
-				// If the parent test is not found, create a new one
-			}
-		}
+// emit delivers the event to the subscribers
+func (p *parser) emit(event *Event) {
+	event.Summary = p.sum
+	for _, handler := range p.handlers {
+		handler(event)
+	}
+}
+
+// parseTestAction handles the actions that are bound to a test
+func (p *parser) parseTestAction(action *Action) *Event {
+	pkg := p.getPackage(action.Package)
 
+	switch action.Action {
+	case "run":
+		return &Event{Type: EventTestRun, Package: pkg, Test: p.startTest(action)}
+	case "output":
+		test := p.getTest(action)
+		test.Output = append(test.Output, action.Output)
+		return &Event{Type: EventTestOutput, Package: pkg, Test: test}
+	case "pause":
+		return &Event{Type: EventTestPause, Package: pkg, Test: p.getTest(action)}
+	case "cont":
+		return &Event{Type: EventTestCont, Package: pkg, Test: p.getTest(action)}
 	case "pass":
-		if action.Test == "" {
-			break
+		test := p.endTest(action)
+		test.EndTime = action.Time
+		test.ElapsedTime = action.Elapsed
+		test.Status = StatusPass
+		if !checkSubtestsPassed(test) {
+			test.Status = StatusFail
+		}
+		p.testCounts(action.Test).add(test.Status)
+		return &Event{Type: EventTestEnd, Package: pkg, Test: test}
+	case "skip":
+		test := p.endTest(action)
+		test.EndTime = action.Time
+		test.ElapsedTime = action.Elapsed
+		test.Status = StatusSkip
+		test.SkipReason = extractSkipReason(test.Output)
+		p.testCounts(action.Test).add(test.Status)
+		return &Event{Type: EventTestEnd, Package: pkg, Test: test}
+	case "fail":
+		test := p.endTest(action)
+		test.EndTime = action.Time
+		test.ElapsedTime = action.Elapsed
+		test.Status = StatusFail
+		if hasPanic(test.Output) {
+			test.Panic = parsePanic(test.Output, action.Test)
 		}
+		test.Failures = parseFailures(test.Output, test.Panic, hasFailedSubtest(test))
+		p.testCounts(action.Test).add(test.Status)
 
-		p.currentTest.EndTime = action.Time
-		p.currentTest.ElapsedTime = action.Elapsed
-		p.currentTest.IsPassed = checkSubtestsPassed(p.currentTest)
+		p.setParentTestsFailed(action.Package, action.Test)
+		return &Event{Type: EventTestEnd, Package: pkg, Test: test}
+	}
 
-		if v, ok := p.currentPackage.TestResults[action.Test]; ok {
-			v.IsPassed = p.currentTest.IsPassed
+	return nil
+}
+
+// parsePackageAction handles the actions that are not bound to a test
+func (p *parser) parsePackageAction(action *Action) *Event {
+	switch action.Action {
+	case "start":
+		pkg := p.getPackage(action.Package)
+		pkg.StartTime = action.Time
+		return &Event{Type: EventPackageStart, Package: pkg}
+	case "output":
+		pkg := p.getPackage(action.Package)
+		pkg.Output = append(pkg.Output, action.Output)
+		if coverage, ok := parseCoverage(action.Output); ok {
+			pkg.Coverage = &coverage
 		}
+		return &Event{Type: EventPackageOutput, Package: pkg}
 	case "skip":
-		p.currentPackage = &PackageResult{
-			TestResults: make(map[string]*TestResult), // Initialize the map
+		// the package has no test files, there is nothing to report
+		pkg := p.getPackage(action.Package)
+		p.sum.Packages.Total++
+		p.sum.Packages.add(StatusSkip)
+		p.dropPackage(action.Package)
+		return &Event{Type: EventPackageSkip, Package: pkg}
+	case "pass", "fail":
+		pkg := p.getPackage(action.Package)
+		pkg.EndTime = action.Time
+		pkg.ElapsedTime = action.Elapsed
+		if action.FailedBuild != "" {
+			p.setFailedBuild(pkg, action.FailedBuild)
+		} else {
+			p.setFailedTextBuild(pkg)
 		}
-		p.currentTest = &TestResult{}
+		p.failRunningTests(pkg, action)
+		p.finishPackage(pkg, action.Action == "pass" && !pkg.BuildFailed)
+		return &Event{Type: EventPackageEnd, Package: pkg}
+	}
+
+	return nil
+}
+
+// finishPackage counts the finished package and adds it to the summary
+func (p *parser) finishPackage(pkg *PackageResult, passed bool) {
+	pkg.IsPassed = passed && !hasFailedTests(pkg)
+	p.countPackage(pkg, 1)
+
+	p.sum.PackageResults = append(p.sum.PackageResults, pkg)
+	p.dropPackage(pkg.PackageName)
+}
+
+// countPackage adds the finished package to the counters of the summary, or
+// removes it from them when n is -1
+func (p *parser) countPackage(pkg *PackageResult, n int) {
+	p.sum.Packages.Total += n
+	if pkg.IsPassed {
+		p.sum.Packages.Passed += n
 		return
+	}
 
-	case "fail":
-		if action.Test == "" {
-			break
+	p.sum.Packages.Failed += n
+	switch {
+	case pkg.BuildFailed:
+		p.sum.BuildFailures += n
+	case !hasFailedTests(pkg):
+		p.sum.PackageFailures += n
+	}
+}
+
+// hasFailedTests reports whether a test of the package failed
+func hasFailedTests(pkg *PackageResult) bool {
+	for _, test := range pkg.TestResults {
+		if test.Status == StatusFail {
+			return true
 		}
+	}
+	return false
+}
 
-		p.currentTest.EndTime = action.Time
-		p.currentTest.ElapsedTime = action.Elapsed
-		p.currentTest.IsPassed = false
-		p.sum.TotalFailed++
-		p.sum.TotalPassed--
+// startTest registers a new test and attaches it to its parent test or package
+func (p *parser) startTest(action *Action) *TestResult {
+	testNames := strings.Split(action.Test, "/")
+	test := &TestResult{
+		TestName:  testNames[len(testNames)-1],
+		StartTime: action.Time,
+		Status:    StatusPass,
+	}
+	p.tests[testKey{pkg: action.Package, test: action.Test}] = test
+	p.running[testKey{pkg: action.Package, test: action.Test}] = test
+	p.testCounts(action.Test).Total++
 
-		if p.currentPackage.PackageName == action.Package {
-			p.currentPackage.IsPassed = false
-		}
+	if parent := p.findParentTest(action.Package, action.Test); parent != nil {
+		parent.Subtests = append(parent.Subtests, test)
+	} else {
+		pkg := p.getPackage(action.Package)
+		pkg.TestResults = append(pkg.TestResults, test)
+	}
+
+	return test
+}
 
-		setParentTestsFailed(action.Test, p.currentPackage.TestResults)
+// testCounts returns the counters of the level the given test belongs to
+func (p *parser) testCounts(testName string) *Counts {
+	if strings.Contains(testName, "/") {
+		return &p.sum.Subtests
 	}
+	return &p.sum.Tests
+}
 
-	// If the package test ended
-	if (action.Action == "pass" || action.Action == "fail") && action.Test == "" {
-		p.currentPackage.EndTime = action.Time
-		p.currentPackage.ElapsedTime = action.Elapsed
-		p.currentPackage.IsPassed = true
-		for _, test := range p.currentPackage.TestResults {
-			if !test.IsPassed {
-				p.currentPackage.IsPassed = false
-				break
-			}
-		}
+// getTest returns the test the action belongs to, registering it if the run
+// action has not been seen
+func (p *parser) getTest(action *Action) *TestResult {
+	if test, ok := p.tests[testKey{pkg: action.Package, test: action.Test}]; ok {
+		return test
+	}
+	return p.startTest(action)
+}
+
+// endTest returns the test the end action belongs to and marks it as finished
+func (p *parser) endTest(action *Action) *TestResult {
+	test := p.getTest(action)
+	delete(p.running, testKey{pkg: action.Package, test: action.Test})
+	return test
+}
+
+// getPackage returns the in-progress result of the given package, creating it if needed
+func (p *parser) getPackage(name string) *PackageResult {
+	if pkg, ok := p.packages[name]; ok {
+		return pkg
+	}
 
-		p.sum.PackageResults = append(p.sum.PackageResults, *p.currentPackage)
-		p.currentPackage = &PackageResult{
-			TestResults: make(map[string]*TestResult), // Initialize the map
+	pkg := &PackageResult{
+		PackageName: name,
+	}
+	p.packages[name] = pkg
+	return pkg
+}
+
+// dropPackage forgets the in-progress state of the given package
+func (p *parser) dropPackage(name string) {
+	delete(p.packages, name)
+	for key := range p.tests {
+		if key.pkg == name {
+			delete(p.tests, key)
+			delete(p.running, key)
 		}
 	}
 }
 
-func setParentTestsFailed(testName string, testResults map[string]*TestResult) {
+// findParentTest returns the closest registered ancestor of the given test
+func (p *parser) findParentTest(pkg string, testName string) *TestResult {
 	parts := strings.Split(testName, "/")
 	for i := len(parts) - 1; i > 0; i-- {
-		parentTestName := strings.Join(parts[:i], "/")
-		parentTest := findTest(parentTestName, testResults)
-		if parentTest != nil {
-			parentTest.IsPassed = false
+		if parent, ok := p.tests[testKey{pkg: pkg, test: strings.Join(parts[:i], "/")}]; ok {
+			return parent
 		}
 	}
+	return nil
 }
 
-func checkSubtestsPassed(test *TestResult) bool {
-	for _, subTest := range test.Subtests {
-		if !subTest.IsPassed || !checkSubtestsPassed(subTest) {
-			return false
+// setParentTestsFailed marks every ancestor of the given test as failed
+func (p *parser) setParentTestsFailed(pkg string, testName string) {
+	parts := strings.Split(testName, "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		if parent, ok := p.tests[testKey{pkg: pkg, test: strings.Join(parts[:i], "/")}]; ok {
+			parent.Status = StatusFail
 		}
 	}
-	return true
 }
 
-func findTest(testName string, testResults map[string]*TestResult) *TestResult {
-	if result, ok := testResults[testName]; ok {
-		return result
-	}
-
-	for _, result := range testResults {
-		for _, subTest := range result.Subtests {
-			if foundTest := findTest(testName, map[string]*TestResult{subTest.TestName: subTest}); foundTest != nil {
-				return foundTest
-			}
+// extractSkipReason returns the message logged by t.Skip, without the
+// framing lines and the file:line prefix
+func extractSkipReason(output []string) string {
+	var reasons []string
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- SKIP") {
+			continue
+		}
+		if m := skipLocationRe.FindStringSubmatch(line); m != nil {
+			line = m[1]
 		}
+		reasons = append(reasons, line)
 	}
+	return strings.Join(reasons, "\n")
+}
 
-	if strings.Contains(testName, "/") {
-		testNames := strings.Split(testName, "/")
-		testName = testNames[len(testNames)-1]
-		return findTest(testName, testResults)
+// coverageRe matches the coverage line go test prints for every package run with -cover
+var coverageRe = regexp.MustCompile(`coverage: (\d+(?:\.\d+)?)% of statements`)
+
+// parseCoverage returns the percentage from a coverage output line
+func parseCoverage(line string) (float64, bool) {
+	m := coverageRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return 0, false
+	}
+	coverage, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
 	}
+	return coverage, true
+}
 
-	return nil
+// skipLocationRe matches the file:line prefix the testing package adds to logged messages
+var skipLocationRe = regexp.MustCompile(`^[\w.\-/]+\.go:\d+: (.*)$`)
+
+func checkSubtestsPassed(test *TestResult) bool {
+	for _, subTest := range test.Subtests {
+		if subTest.Status == StatusFail || !checkSubtestsPassed(subTest) {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *parser) GetSummary() *Summary {
+	p.finishBuildFailures()
 	return p.sum
 }
 
@@ -183,16 +370,35 @@ type Action struct {
 	Test    string    `json:"Test"`
 	Output  string    `json:"Output"`
 	Elapsed float64   `json:"Elapsed"`
+
+	// ImportPath identifies the build of build-output and build-fail actions
+	// (go 1.24+), e.g. "example.com/pkg [example.com/pkg.test]"
+	ImportPath string `json:"ImportPath"`
+	// FailedBuild is set on the fail action of a package that could not be
+	// built, to the ImportPath of the build that failed (go 1.24+)
+	FailedBuild string `json:"FailedBuild"`
 }
 
+// Status is the outcome of a test
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
 type TestResult struct {
 	TestName    string
 	StartTime   time.Time
 	EndTime     time.Time
 	ElapsedTime float64
-	IsPassed    bool
+	Status      Status
+	SkipReason  string    // message passed to t.Skip, if the test was skipped
+	Panic       *Panic    // the panic or timeout that failed the test, if any
+	Failures    []Failure // the failures reported by the test, with their location
 	Output      []string
-	Subtests    []*TestResult // This line is new
+	Subtests    []*TestResult // in the order they started
 }
 
 type PackageResult struct {
@@ -202,12 +408,104 @@ type PackageResult struct {
 	ElapsedTime float64
 	IsPassed    bool
 	Output      []string
-	TestResults map[string]*TestResult // Change this from slice to map
+	Coverage    *float64 // percent of covered statements, nil if not reported
+	BuildFailed bool
+	Diagnostics []Diagnostic  // the errors of the build, if the package failed to build
+	Panic       *Panic        // a panic outside of any test, e.g. in TestMain or init
+	TestResults []*TestResult // top-level tests, in the order they started
+}
+
+// Counts holds the number of results at one level of the summary
+type Counts struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+// add counts a finished result with the given status
+func (c *Counts) add(status Status) {
+	switch status {
+	case StatusPass:
+		c.Passed++
+	case StatusFail:
+		c.Failed++
+	case StatusSkip:
+		c.Skipped++
+	}
 }
 
 type Summary struct {
-	TotalPackages  int
-	TotalPassed    int
-	TotalFailed    int
-	PackageResults []PackageResult
+	Packages Counts // skipped packages are the ones without test files
+	Tests    Counts // top-level tests
+	Subtests Counts
+
+	// PackageFailures is the number of packages that failed without a failed
+	// test, e.g. because TestMain or an init function panicked
+	PackageFailures int
+
+	// BuildFailures is the number of packages that failed to build
+	BuildFailures int
+
+	PackageResults []*PackageResult
+
+	// Profile is the coverage profile of the run, nil if none was collected
+	Profile *coveragepkg.Profile
+}
+
+// TotalCoverage returns the coverage of the run. It is weighted by the number
+// of statements when a coverage profile was collected, otherwise every package
+// that reported coverage is weighted equally. The second value is false if no
+// coverage was reported.
+func (s *Summary) TotalCoverage() (float64, bool) {
+	if s.Profile != nil {
+		if total, covered := s.Profile.Statements(); total > 0 {
+			return coveragepkg.Percent(total, covered), true
+		}
+	}
+
+	var total float64
+	var count int
+	for _, pkg := range s.PackageResults {
+		if pkg.Coverage != nil {
+			total += *pkg.Coverage
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// TimedOut reports whether a package was aborted because its tests exceeded the -timeout of go test
+func (s *Summary) TimedOut() bool {
+	for _, pkg := range s.PackageResults {
+		if pkg.Panic != nil && pkg.Panic.Timeout {
+			return true
+		}
+
+		timedOut := false
+		var check func(test *TestResult)
+		check = func(test *TestResult) {
+			if test.Panic != nil && test.Panic.Timeout {
+				timedOut = true
+			}
+			for _, subtest := range test.Subtests {
+				check(subtest)
+			}
+		}
+		for _, test := range pkg.TestResults {
+			check(test)
+		}
+		if timedOut {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPassed reports whether no package, test or subtest failed
+func (s *Summary) IsPassed() bool {
+	return s.Packages.Failed == 0 && s.Tests.Failed == 0 && s.Subtests.Failed == 0
 }
@@ -0,0 +1,432 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		name            string
+		file            string
+		packages        Counts
+		tests           Counts
+		subtests        Counts
+		packageFailures int
+		buildFailures   int
+		statuses        map[string]Status
+		check           func(t *testing.T, sum *Summary)
+	}{
+		{
+			name:     "interleaved packages",
+			file:     "interleaved.json",
+			packages: Counts{Total: 2, Passed: 2},
+			tests:    Counts{Total: 4, Passed: 4},
+			statuses: map[string]Status{
+				"example.com/rec/il1 TestA": StatusPass,
+				"example.com/rec/il1 TestB": StatusPass,
+				"example.com/rec/il2 TestA": StatusPass,
+				"example.com/rec/il2 TestB": StatusPass,
+			},
+			check: func(t *testing.T, sum *Summary) {
+				for _, pkg := range sum.PackageResults {
+					for _, test := range pkg.TestResults {
+						for _, line := range test.Output {
+							assert.NotContains(t, line, "ok  ", "%s %s", pkg.PackageName, test.TestName)
+						}
+					}
+				}
+			},
+		},
+		{
+			name:     "parallel tests, skips and nested subtests",
+			file:     "parallel.json",
+			packages: Counts{Total: 1, Failed: 1},
+			tests:    Counts{Total: 4, Passed: 1, Failed: 2, Skipped: 1},
+			subtests: Counts{Total: 5, Passed: 1, Failed: 4},
+			statuses: map[string]Status{
+				"example.com/rec/par TestParallel":        StatusFail,
+				"example.com/rec/par TestParallel/p1":     StatusPass,
+				"example.com/rec/par TestParallel/p2":     StatusFail,
+				"example.com/rec/par TestSkip":            StatusSkip,
+				"example.com/rec/par TestLevels":          StatusFail,
+				"example.com/rec/par TestLevels/l1":       StatusFail,
+				"example.com/rec/par TestLevels/l1/l2":    StatusFail,
+				"example.com/rec/par TestLevels/l1/l2/l3": StatusFail,
+				"example.com/rec/par TestPass":            StatusPass,
+			},
+			check: func(t *testing.T, sum *Summary) {
+				tests := sum.PackageResults[0].TestResults
+				assert.Equal(t, "not supported here", tests[1].SkipReason)
+
+				l3 := tests[2].Subtests[0].Subtests[0].Subtests[0]
+				require.Len(t, l3.Failures, 1)
+				assert.Equal(t, Failure{File: "par_test.go", Line: 29, Message: "deep failure", Kind: FailureError}, l3.Failures[0])
+				assert.Empty(t, tests[2].Failures)
+			},
+		},
+		{
+			name:     "result line of a nested subtest indented by go 1.19",
+			file:     "nested_go119.json",
+			packages: Counts{Total: 1, Failed: 1},
+			tests:    Counts{Total: 1, Failed: 1},
+			subtests: Counts{Total: 2, Failed: 2},
+			statuses: map[string]Status{
+				"example.com/rec/nest TestNested":     StatusFail,
+				"example.com/rec/nest TestNested/g":   StatusFail,
+				"example.com/rec/nest TestNested/g/c": StatusFail,
+			},
+			check: func(t *testing.T, sum *Summary) {
+				c := sum.PackageResults[0].TestResults[0].Subtests[0].Subtests[0]
+				assert.Equal(t, []Failure{{File: "nest_test.go", Line: 8, Message: "nested failure", Kind: FailureError}}, c.Failures)
+			},
+		},
+		{
+			name:            "panic in TestMain",
+			file:            "testmain_panic.json",
+			packages:        Counts{Total: 1, Failed: 1},
+			packageFailures: 1,
+			statuses:        map[string]Status{},
+			check: func(t *testing.T, sum *Summary) {
+				p := sum.PackageResults[0].Panic
+				require.NotNil(t, p)
+				assert.Equal(t, "setup exploded", p.Message)
+				assert.False(t, p.Timeout)
+				require.NotEmpty(t, p.Stack)
+				assert.Equal(t, "example.com/rec/tmain.TestMain", p.Stack[0].Function)
+				assert.True(t, p.Stack[0].User)
+			},
+		},
+		{
+			name:     "timeout",
+			file:     "timeout.json",
+			packages: Counts{Total: 1, Failed: 1},
+			tests:    Counts{Total: 2, Passed: 1, Failed: 1},
+			subtests: Counts{Total: 1, Failed: 1},
+			statuses: map[string]Status{
+				"example.com/rec/tout TestQuick":    StatusPass,
+				"example.com/rec/tout TestSlow":     StatusFail,
+				"example.com/rec/tout TestSlow/sub": StatusFail,
+			},
+			check: func(t *testing.T, sum *Summary) {
+				assert.True(t, sum.TimedOut())
+
+				slow := sum.PackageResults[0].TestResults[1]
+				assert.Nil(t, slow.Panic)
+				p := slow.Subtests[0].Panic
+				require.NotNil(t, p)
+				assert.True(t, p.Timeout)
+				assert.Equal(t, "test timed out after 1s", p.Message)
+			},
+		},
+		{
+			name:          "build failure of go 1.24+",
+			file:          "build_failed.json",
+			packages:      Counts{Total: 1, Failed: 1},
+			buildFailures: 1,
+			statuses:      map[string]Status{},
+			check: func(t *testing.T, sum *Summary) {
+				pkg := sum.PackageResults[0]
+				assert.True(t, pkg.BuildFailed)
+				assert.Equal(t, []Diagnostic{{
+					File:    "bf/bf_test.go",
+					Line:    6,
+					Column:  14,
+					Message: `cannot use "a" (untyped string constant) as int value in variable declaration`,
+				}}, pkg.Diagnostics)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum := parseFile(t, tt.file)
+
+			assert.Equal(t, tt.packages, sum.Packages, "packages")
+			assert.Equal(t, tt.tests, sum.Tests, "tests")
+			assert.Equal(t, tt.subtests, sum.Subtests, "subtests")
+			assert.Equal(t, tt.packageFailures, sum.PackageFailures, "package failures")
+			assert.Equal(t, tt.buildFailures, sum.BuildFailures, "build failures")
+			assert.Equal(t, tt.statuses, statuses(sum))
+			if tt.check != nil {
+				tt.check(t, sum)
+			}
+		})
+	}
+}
+
+func TestParserAttribution(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		packages map[string]bool     // whether the package passed, by name
+		outputs  map[string][]string // lines expected in the output of a test, by package and full name
+	}{
+		{
+			name:     "interleaved packages",
+			file:     "interleaved.json",
+			packages: map[string]bool{"example.com/rec/il1": true, "example.com/rec/il2": true},
+			outputs: map[string][]string{
+				"example.com/rec/il1 TestA": {"--- PASS: TestA"},
+				"example.com/rec/il1 TestB": {"--- PASS: TestB"},
+				"example.com/rec/il2 TestA": {"--- PASS: TestA"},
+				"example.com/rec/il2 TestB": {"--- PASS: TestB"},
+			},
+		},
+		{
+			name:     "parallel tests and nested subtests",
+			file:     "parallel.json",
+			packages: map[string]bool{"example.com/rec/par": false},
+			outputs: map[string][]string{
+				"example.com/rec/par TestParallel/p1":     {"=== PAUSE TestParallel/p1", "=== CONT  TestParallel/p1", "--- PASS: TestParallel/p1"},
+				"example.com/rec/par TestParallel/p2":     {"p2 failed", "--- FAIL: TestParallel/p2"},
+				"example.com/rec/par TestSkip":            {"not supported here", "--- SKIP: TestSkip"},
+				"example.com/rec/par TestLevels/l1/l2/l3": {"deep failure", "--- FAIL: TestLevels/l1/l2/l3"},
+				"example.com/rec/par TestPass":            {"--- PASS: TestPass"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum := parseFile(t, tt.file)
+
+			packages := make(map[string]bool)
+			for _, pkg := range sum.PackageResults {
+				packages[pkg.PackageName] = pkg.IsPassed
+			}
+			assert.Equal(t, tt.packages, packages)
+
+			outputs := testOutputs(sum)
+			for name, lines := range tt.outputs {
+				for _, line := range lines {
+					assert.Contains(t, outputs[name], line, name)
+				}
+			}
+
+			// the frame lines of a test are never attributed to another test
+			for name, output := range outputs {
+				fullName := name[strings.Index(name, " ")+1:]
+				for _, line := range strings.Split(output, "\n") {
+					if strings.HasPrefix(line, "=== ") || strings.HasPrefix(strings.TrimSpace(line), "--- ") {
+						assert.Contains(t, strings.Fields(line), fullName, name)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParserEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		events map[EventType]int
+	}{
+		{
+			name:   "parallel tests pause and continue",
+			file:   "parallel.json",
+			events: map[EventType]int{EventTestRun: 9, EventTestEnd: 9, EventTestPause: 2, EventTestCont: 2},
+		},
+		{
+			// go test reports no result for the tests running at the timeout
+			name:   "tests failed by a timeout end",
+			file:   "timeout.json",
+			events: map[EventType]int{EventTestRun: 3, EventTestEnd: 3, EventTestPause: 0, EventTestCont: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(map[EventType]int)
+			failed := 0
+			sum := parseFile(t, tt.file, func(event *Event) {
+				events[event.Type]++
+				if event.Type == EventTestEnd && event.Action.Action == "fail" {
+					failed++
+				}
+			})
+
+			for eventType, count := range tt.events {
+				assert.Equal(t, count, events[eventType], "events of type %d", eventType)
+			}
+			assert.Equal(t, sum.Tests.Failed+sum.Subtests.Failed, failed, "failed test ends")
+		})
+	}
+}
+
+// TestParseBuildOutput covers the build output that go versions before 1.24
+// print as text, on stderr for the errors and on stdout for the result
+func TestParseBuildOutput(t *testing.T) {
+	tests := []struct {
+		name          string
+		lines         []string
+		packages      Counts
+		buildFailures int
+		diagnostics   map[string][]Diagnostic // of the packages that failed to build
+	}{
+		{
+			name: "linker warning of a package that builds",
+			lines: []string{
+				"# example.com/rec/w.test",
+				"ld: warning: -bind_at_load is deprecated on macOS",
+				`{"Action":"start","Package":"example.com/rec/w"}`,
+				`{"Action":"run","Package":"example.com/rec/w","Test":"TestW"}`,
+				`{"Action":"pass","Package":"example.com/rec/w","Test":"TestW"}`,
+				`{"Action":"pass","Package":"example.com/rec/w"}`,
+			},
+			packages:    Counts{Total: 1, Passed: 1},
+			diagnostics: map[string][]Diagnostic{},
+		},
+		{
+			name: "build failure",
+			lines: []string{
+				"# example.com/rec/bf [example.com/rec/bf.test]",
+				`bf/bf_test.go:6:14: cannot use "a" (untyped string constant) as int value in variable declaration`,
+				"FAIL\texample.com/rec/bf [build failed]",
+			},
+			packages:      Counts{Total: 1, Failed: 1},
+			buildFailures: 1,
+			diagnostics: map[string][]Diagnostic{
+				"example.com/rec/bf": {{
+					File:    "bf/bf_test.go",
+					Line:    6,
+					Column:  14,
+					Message: `cannot use "a" (untyped string constant) as int value in variable declaration`,
+				}},
+			},
+		},
+		{
+			// stderr is read concurrently with stdout
+			name: "build errors read after the package result",
+			lines: []string{
+				`{"Action":"output","Package":"example.com/rec/bf","Output":"FAIL\\texample.com/rec/bf [build failed]\\n"}`,
+				`{"Action":"fail","Package":"example.com/rec/bf"}`,
+				"# example.com/rec/bf [example.com/rec/bf.test]",
+				"bf/bf_test.go:6:14: undefined: x",
+			},
+			packages:      Counts{Total: 1, Failed: 1},
+			buildFailures: 1,
+			diagnostics: map[string][]Diagnostic{
+				"example.com/rec/bf": {{File: "bf/bf_test.go", Line: 6, Column: 14, Message: "undefined: x"}},
+			},
+		},
+		{
+			name: "error without a package header",
+			lines: []string{
+				"# example.com/rec/w.test",
+				"ld: warning: -bind_at_load is deprecated on macOS",
+				"",
+				"no required module provides package example.com/rec/missing",
+				"FAIL\texample.com/rec/missing [setup failed]",
+			},
+			packages:      Counts{Total: 1, Failed: 1},
+			buildFailures: 1,
+			diagnostics: map[string][]Diagnostic{
+				"example.com/rec/missing": {{Message: "no required module provides package example.com/rec/missing"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum := parse(t, strings.NewReader(strings.Join(tt.lines, "\n")))
+
+			assert.Equal(t, tt.packages, sum.Packages, "packages")
+			assert.Equal(t, tt.buildFailures, sum.BuildFailures, "build failures")
+			diagnostics := make(map[string][]Diagnostic)
+			for _, pkg := range sum.PackageResults {
+				if pkg.BuildFailed {
+					diagnostics[pkg.PackageName] = pkg.Diagnostics
+				}
+			}
+			assert.Equal(t, tt.diagnostics, diagnostics)
+		})
+	}
+}
+
+// parseFile feeds the recorded go test -json output of the file in testdata
+// to a parser with the given subscribers
+func parseFile(t *testing.T, name string, handlers ...EventHandler) *Summary {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	defer f.Close()
+
+	return parse(t, f, handlers...)
+}
+
+// parse feeds the go test -json output to a parser with the given
+// subscribers, the lines that are not JSON are parsed as build output
+func parse(t *testing.T, r io.Reader, handlers ...EventHandler) *Summary {
+	t.Helper()
+
+	p := NewParser()
+	for _, handler := range handlers {
+		p.Subscribe(handler)
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "{") {
+			p.ParseBuildOutput(line)
+			continue
+		}
+
+		var action Action
+		require.NoError(t, json.Unmarshal([]byte(line), &action))
+		p.Parse(&action)
+	}
+	require.NoError(t, scanner.Err())
+
+	return p.GetSummary()
+}
+
+// statuses returns the status of every test of the summary keyed by its
+// package and full name
+func statuses(sum *Summary) map[string]Status {
+	result := make(map[string]Status)
+	var walk func(pkg string, fullName string, test *TestResult)
+	walk = func(pkg string, fullName string, test *TestResult) {
+		result[pkg+" "+fullName] = test.Status
+		for _, subtest := range test.Subtests {
+			walk(pkg, fullName+"/"+subtest.TestName, subtest)
+		}
+	}
+
+	for _, pkg := range sum.PackageResults {
+		for _, test := range pkg.TestResults {
+			walk(pkg.PackageName, test.TestName, test)
+		}
+	}
+	return result
+}
+
+// testOutputs returns the joined output of every test of the summary keyed
+// by its package and full name
+func testOutputs(sum *Summary) map[string]string {
+	result := make(map[string]string)
+	var walk func(pkg string, fullName string, test *TestResult)
+	walk = func(pkg string, fullName string, test *TestResult) {
+		result[pkg+" "+fullName] = strings.Join(test.Output, "")
+		for _, subtest := range test.Subtests {
+			walk(pkg, fullName+"/"+subtest.TestName, subtest)
+		}
+	}
+
+	for _, pkg := range sum.PackageResults {
+		for _, test := range pkg.TestResults {
+			walk(pkg.PackageName, test.TestName, test)
+		}
+	}
+	return result
+}
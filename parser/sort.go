@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortOrder is the order in which packages and tests are listed
+type SortOrder string
+
+const (
+	SortExecution SortOrder = ""         // the order in which packages finished and tests started
+	SortName      SortOrder = "name"     // alphabetical
+	SortDuration  SortOrder = "duration" // slowest first
+	SortStatus    SortOrder = "status"   // failures first, then skips, then passes
+)
+
+// SortOrders returns the orders that can be requested by name
+func SortOrders() []string {
+	return []string{string(SortName), string(SortDuration), string(SortStatus)}
+}
+
+// ParseSortOrder returns the sort order with the given name, an empty name
+// keeps the execution order
+func ParseSortOrder(name string) (SortOrder, error) {
+	switch order := SortOrder(name); order {
+	case SortExecution, SortName, SortDuration, SortStatus:
+		return order, nil
+	}
+	return "", fmt.Errorf("unknown sort order %q, available orders: %s", name, strings.Join(SortOrders(), ", "))
+}
+
+// Sort orders the packages, tests and subtests of the summary. Results that
+// are equal for the given order keep their execution order.
+func (s *Summary) Sort(order SortOrder) {
+	if order == SortExecution {
+		return
+	}
+
+	sort.SliceStable(s.PackageResults, func(i, j int) bool {
+		a, b := s.PackageResults[i], s.PackageResults[j]
+		switch order {
+		case SortName:
+			return a.PackageName < b.PackageName
+		case SortDuration:
+			return a.ElapsedTime > b.ElapsedTime
+		case SortStatus:
+			return !a.IsPassed && b.IsPassed
+		}
+		return false
+	})
+
+	for _, pkg := range s.PackageResults {
+		sortTests(pkg.TestResults, order)
+	}
+}
+
+// sortTests orders the given tests and their subtests
+func sortTests(tests []*TestResult, order SortOrder) {
+	sort.SliceStable(tests, func(i, j int) bool {
+		a, b := tests[i], tests[j]
+		switch order {
+		case SortName:
+			return a.TestName < b.TestName
+		case SortDuration:
+			return a.ElapsedTime > b.ElapsedTime
+		case SortStatus:
+			return statusRank(a.Status) < statusRank(b.Status)
+		}
+		return false
+	})
+
+	for _, test := range tests {
+		sortTests(test.Subtests, order)
+	}
+}
+
+// statusRank returns the position of the status when failures are listed first
+func statusRank(status Status) int {
+	switch status {
+	case StatusFail:
+		return 0
+	case StatusSkip:
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,230 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	coveragepkg "github.com/cjp2600/trep/coverage"
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+func init() {
+	Register(coberturaReporter{})
+}
+
+// coberturaReporter exports the coverage profile as Cobertura XML
+type coberturaReporter struct{}
+
+func (coberturaReporter) Format() string {
+	return "cobertura"
+}
+
+func (coberturaReporter) Extension() string {
+	return "cobertura.xml"
+}
+
+func (coberturaReporter) exportsProfile() {}
+
+// coberturaCoverage is the root element of a Cobertura report
+type coberturaCoverage struct {
+	XMLName         xml.Name             `xml:"coverage"`
+	LineRate        string               `xml:"line-rate,attr"`
+	BranchRate      string               `xml:"branch-rate,attr"`
+	LinesCovered    int                  `xml:"lines-covered,attr"`
+	LinesValid      int                  `xml:"lines-valid,attr"`
+	BranchesCovered int                  `xml:"branches-covered,attr"`
+	BranchesValid   int                  `xml:"branches-valid,attr"`
+	Complexity      int                  `xml:"complexity,attr"`
+	Version         string               `xml:"version,attr"`
+	Timestamp       int64                `xml:"timestamp,attr"`
+	Sources         []string             `xml:"sources>source"`
+	Packages        coberturaPackageList `xml:"packages"`
+}
+
+// The lists below are their own elements as the DTD requires them even when
+// they are empty, e.g. the methods of a file without functions
+
+type coberturaPackageList struct {
+	Package []coberturaPackage `xml:"package"`
+}
+
+type coberturaMethodList struct {
+	Method []coberturaMethod `xml:"method"`
+}
+
+type coberturaLineList struct {
+	Line []coberturaLine `xml:"line"`
+}
+
+// coberturaPackage holds the files of a Go package
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity int              `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+// coberturaClass holds a single source file
+type coberturaClass struct {
+	Name       string              `xml:"name,attr"`
+	Filename   string              `xml:"filename,attr"`
+	LineRate   string              `xml:"line-rate,attr"`
+	BranchRate string              `xml:"branch-rate,attr"`
+	Complexity int                 `xml:"complexity,attr"`
+	Methods    coberturaMethodList `xml:"methods"`
+	Lines      coberturaLineList   `xml:"lines"`
+}
+
+// coberturaMethod holds a function of a file
+type coberturaMethod struct {
+	Name       string            `xml:"name,attr"`
+	Signature  string            `xml:"signature,attr"`
+	LineRate   string            `xml:"line-rate,attr"`
+	BranchRate string            `xml:"branch-rate,attr"`
+	Complexity int               `xml:"complexity,attr"`
+	Lines      coberturaLineList `xml:"lines"`
+}
+
+// coberturaLine holds the execution count of a line
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// Generate converts the coverage profile to Cobertura XML. File names are
+// relative to the working directory, which is listed as the only source.
+func (coberturaReporter) Generate(sum *parserpkg.Summary) ([]byte, error) {
+	root, _ := os.Getwd()
+	report := coberturaCoverage{
+		BranchRate: formatRate(0, 0),
+		Timestamp:  time.Now().UnixNano() / int64(time.Millisecond),
+		Sources:    []string{root},
+	}
+
+	packages := make(map[string]*coberturaPackage)
+	var packageNames []string
+	packageLines := make(map[string][2]int)
+
+	for _, f := range profileFiles(sum) {
+		pkgName := f.Package()
+		pkg, ok := packages[pkgName]
+		if !ok {
+			pkg = &coberturaPackage{Name: pkgName, BranchRate: formatRate(0, 0)}
+			packages[pkgName] = pkg
+			packageNames = append(packageNames, pkgName)
+		}
+
+		hits := f.LineHits()
+		valid, covered := countLines(hits)
+
+		class := coberturaClass{
+			Name:       path.Base(f.Name),
+			Filename:   relativeFilename(root, f),
+			LineRate:   formatRate(valid, covered),
+			BranchRate: formatRate(0, 0),
+			Lines:      coberturaLineList{Line: coberturaLines(hits, 0, 0)},
+		}
+		for _, fn := range f.Functions {
+			fnLines := coberturaLines(hits, fn.StartLine, fn.EndLine)
+			fnHits := make(map[int]int, len(fnLines))
+			for _, l := range fnLines {
+				fnHits[l.Number] = l.Hits
+			}
+			fnValid, fnCovered := countLines(fnHits)
+			class.Methods.Method = append(class.Methods.Method, coberturaMethod{
+				Name:       fn.Name,
+				LineRate:   formatRate(fnValid, fnCovered),
+				BranchRate: formatRate(0, 0),
+				Lines:      coberturaLineList{Line: fnLines},
+			})
+		}
+		pkg.Classes = append(pkg.Classes, class)
+
+		counts := packageLines[pkgName]
+		packageLines[pkgName] = [2]int{counts[0] + valid, counts[1] + covered}
+		report.LinesValid += valid
+		report.LinesCovered += covered
+	}
+
+	for _, name := range packageNames {
+		pkg := packages[name]
+		counts := packageLines[name]
+		pkg.LineRate = formatRate(counts[0], counts[1])
+		report.Packages.Package = append(report.Packages.Package, *pkg)
+	}
+	report.LineRate = formatRate(report.LinesValid, report.LinesCovered)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">` + "\n")
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// profileFiles returns the files of the coverage profile, none if the run has
+// no profile, e.g. because its packages failed to build
+func profileFiles(sum *parserpkg.Summary) []*coveragepkg.File {
+	if sum.Profile == nil {
+		return nil
+	}
+	return sum.Profile.Files
+}
+
+// coberturaLines returns the lines with statements between the given lines,
+// or all lines when the range is empty
+func coberturaLines(hits map[int]int, from int, to int) []coberturaLine {
+	var lines []coberturaLine
+	for _, n := range coveragepkg.SortedLines(hits) {
+		if to > 0 && (n < from || n > to) {
+			continue
+		}
+		lines = append(lines, coberturaLine{Number: n, Hits: hits[n]})
+	}
+	return lines
+}
+
+// countLines returns the number of lines with statements and the number of executed ones
+func countLines(hits map[int]int) (valid int, covered int) {
+	for _, count := range hits {
+		valid++
+		if count > 0 {
+			covered++
+		}
+	}
+	return valid, covered
+}
+
+// formatRate formats the covered part of the lines as a Cobertura rate between 0 and 1
+func formatRate(valid int, covered int) string {
+	if valid == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.4f", float64(covered)/float64(valid))
+}
+
+// relativeFilename returns the path of the file relative to the root if its
+// source was found inside it, the name from the profile otherwise
+func relativeFilename(root string, f *coveragepkg.File) string {
+	if f.Path == "" || root == "" {
+		return f.Name
+	}
+
+	rel, err := filepath.Rel(root, f.Path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return f.Path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+
+	coveragepkg "github.com/cjp2600/trep/coverage"
+)
+
+// coverageFile is a file of the coverage profile listed in the report
+type coverageFile struct {
+	ID         string
+	Name       string
+	Statements int
+	Covered    int
+	Percent    float64
+	Lines      []coverageLine // annotated source, empty if the source was not found
+}
+
+// coverageFunction is a function of the coverage profile listed in the report
+type coverageFunction struct {
+	File       string
+	FileID     string
+	Name       string
+	Line       int
+	Statements int
+	Covered    int
+	Percent    float64
+}
+
+// coverageLine is a line of annotated source
+type coverageLine struct {
+	Number int
+	Text   string
+	Class  string // covered, uncovered, partial or empty if the line has no statements
+}
+
+// getCoverageFiles returns the files of the coverage profile with their annotated sources
+func getCoverageFiles(profile *coveragepkg.Profile) []coverageFile {
+	if profile == nil {
+		return nil
+	}
+
+	var files []coverageFile
+	for i, f := range profile.Files {
+		total, covered := f.Statements()
+		files = append(files, coverageFile{
+			ID:         fmt.Sprintf("file-%d", i),
+			Name:       f.Name,
+			Statements: total,
+			Covered:    covered,
+			Percent:    coveragepkg.Percent(total, covered),
+			Lines:      annotateSource(f),
+		})
+	}
+	return files
+}
+
+// getCoverageFunctions returns the functions of the coverage profile, least covered first
+func getCoverageFunctions(profile *coveragepkg.Profile) []coverageFunction {
+	if profile == nil {
+		return nil
+	}
+
+	var functions []coverageFunction
+	for i, f := range profile.Files {
+		for _, fn := range f.Functions {
+			functions = append(functions, coverageFunction{
+				File:       f.Name,
+				FileID:     fmt.Sprintf("file-%d", i),
+				Name:       fn.Name,
+				Line:       fn.StartLine,
+				Statements: fn.Statements,
+				Covered:    fn.Covered,
+				Percent:    coveragepkg.Percent(fn.Statements, fn.Covered),
+			})
+		}
+	}
+
+	sort.SliceStable(functions, func(i, j int) bool {
+		return functions[i].Percent < functions[j].Percent
+	})
+	return functions
+}
+
+// annotateSource reads the source of the file and marks every line by the
+// coverage of the blocks it belongs to
+func annotateSource(f *coveragepkg.File) []coverageLine {
+	if f.Path == "" {
+		return nil
+	}
+
+	src, err := os.Open(f.Path)
+	if err != nil {
+		return nil
+	}
+	defer src.Close()
+
+	var lines []coverageLine
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		lines = append(lines, coverageLine{Number: n, Text: scanner.Text()})
+	}
+
+	covered := make(map[int]bool)
+	uncovered := make(map[int]bool)
+	for _, b := range f.Blocks {
+		if b.NumStmt == 0 {
+			continue
+		}
+		for n := b.StartLine; n <= b.EndLine; n++ {
+			if b.Count > 0 {
+				covered[n] = true
+			} else {
+				uncovered[n] = true
+			}
+		}
+	}
+
+	for i := range lines {
+		n := lines[i].Number
+		switch {
+		case covered[n] && uncovered[n]:
+			lines[i].Class = "partial"
+		case covered[n]:
+			lines[i].Class = "covered"
+		case uncovered[n]:
+			lines[i].Class = "uncovered"
+		}
+	}
+
+	return lines
+}
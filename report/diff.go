@@ -0,0 +1,94 @@
+package report
+
+import (
+	"strings"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+// sideBySideDiff is the comparison of an assertion shown with the expected
+// value on the left and the actual value on the right
+type sideBySideDiff struct {
+	Title string
+	Rows  []diffRow
+}
+
+// diffRow is a line of a side-by-side diff. The class of a side is
+// "removed", "added" or "hunk", or empty for the lines that did not change.
+type diffRow struct {
+	Left       string
+	LeftClass  string
+	Right      string
+	RightClass string
+}
+
+// getSideBySideDiffs returns the side-by-side diffs of the failures that compare values
+func getSideBySideDiffs(failures []parserpkg.Failure) []sideBySideDiff {
+	var diffs []sideBySideDiff
+	for _, f := range failures {
+		if len(f.Diff) == 0 && f.Expected == "" && f.Actual == "" {
+			continue
+		}
+
+		diff := sideBySideDiff{Title: strings.TrimSpace(strings.SplitN(f.Message, "\n", 2)[0])}
+		if len(f.Diff) > 0 {
+			diff.Rows = buildDiffRows(f.Diff)
+		} else {
+			diff.Rows = pairDiffRows(strings.Split(f.Expected, "\n"), strings.Split(f.Actual, "\n"))
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// buildDiffRows converts the lines of a unified diff into side-by-side rows,
+// pairing the removed lines with the added lines that follow them
+func buildDiffRows(lines []string) []diffRow {
+	var rows []diffRow
+	var removed, added []string
+	flush := func() {
+		rows = append(rows, pairDiffRows(removed, added)...)
+		removed, added = nil, nil
+	}
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			rows = append(rows, diffRow{Left: line, LeftClass: "hunk", Right: line, RightClass: "hunk"})
+		case strings.HasPrefix(line, "-"):
+			removed = append(removed, line[1:])
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		default:
+			flush()
+			text := strings.TrimPrefix(line, " ")
+			rows = append(rows, diffRow{Left: text, Right: text})
+		}
+	}
+	flush()
+
+	return rows
+}
+
+// pairDiffRows puts the removed and added lines side by side, leaving the
+// cells of the shorter side empty
+func pairDiffRows(removed, added []string) []diffRow {
+	n := len(removed)
+	if len(added) > n {
+		n = len(added)
+	}
+
+	rows := make([]diffRow, 0, n)
+	for i := 0; i < n; i++ {
+		var row diffRow
+		if i < len(removed) {
+			row.Left, row.LeftClass = removed[i], "removed"
+		}
+		if i < len(added) {
+			row.Right, row.RightClass = added[i], "added"
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
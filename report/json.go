@@ -0,0 +1,242 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+// JSONSchemaVersion is the version of the JSON report schema. It is increased
+// whenever a field is removed or changes its meaning; new fields may be added
+// without a version change.
+const JSONSchemaVersion = 1
+
+// jsonReport is the root object of the JSON report
+type jsonReport struct {
+	SchemaVersion int           `json:"schema_version"`
+	GeneratedAt   time.Time     `json:"generated_at"`
+	Passed        bool          `json:"passed"`
+	Counts        jsonCounts    `json:"counts"`
+	Coverage      *float64      `json:"coverage"`
+	Packages      []jsonPackage `json:"packages"`
+}
+
+// jsonCounts holds the counters of the summary
+type jsonCounts struct {
+	Packages        jsonCount `json:"packages"`
+	Tests           jsonCount `json:"tests"`
+	Subtests        jsonCount `json:"subtests"`
+	PackageFailures int       `json:"package_failures"`
+	BuildFailures   int       `json:"build_failures"`
+}
+
+// jsonCount holds the counters of one level of the summary
+type jsonCount struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// jsonPackage holds the result of a package
+type jsonPackage struct {
+	Name           string           `json:"name"`
+	Status         string           `json:"status"`
+	StartTime      time.Time        `json:"start_time"`
+	EndTime        time.Time        `json:"end_time"`
+	Elapsed        float64          `json:"elapsed"`
+	Coverage       *float64         `json:"coverage"`
+	FailureMessage string           `json:"failure_message,omitempty"`
+	Diagnostics    []jsonDiagnostic `json:"diagnostics,omitempty"`
+	Panic          *jsonPanic       `json:"panic,omitempty"`
+	Output         []string         `json:"output"`
+	Tests          []jsonTest       `json:"tests"`
+}
+
+// jsonFailure holds a failure reported by a test with its location
+type jsonFailure struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Message  string   `json:"message"`
+	Kind     string   `json:"kind"`
+	Expected string   `json:"expected,omitempty"`
+	Actual   string   `json:"actual,omitempty"`
+	Diff     []string `json:"diff,omitempty"`
+}
+
+// jsonPanic holds a panic, or timeout, that failed a test or package
+type jsonPanic struct {
+	Message string      `json:"message"`
+	Timeout bool        `json:"timeout"`
+	Stack   []jsonFrame `json:"stack"`
+}
+
+// jsonFrame holds a function call of a panic stack
+type jsonFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	User     bool   `json:"user"`
+}
+
+// jsonDiagnostic holds a build error of a package
+type jsonDiagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// jsonTest holds the result of a test or subtest
+type jsonTest struct {
+	Name           string        `json:"name"`
+	FullName       string        `json:"full_name"`
+	Status         string        `json:"status"`
+	StartTime      time.Time     `json:"start_time"`
+	EndTime        time.Time     `json:"end_time"`
+	Elapsed        float64       `json:"elapsed"`
+	FailureMessage string        `json:"failure_message,omitempty"`
+	Panic          *jsonPanic    `json:"panic,omitempty"`
+	Failures       []jsonFailure `json:"failures,omitempty"`
+	SkipReason     string        `json:"skip_reason,omitempty"`
+	Output         []string      `json:"output"`
+	Subtests       []jsonTest    `json:"subtests"`
+}
+
+func init() {
+	Register(jsonReporter{})
+}
+
+// jsonReporter renders the summary as a versioned JSON document
+type jsonReporter struct{}
+
+func (jsonReporter) Format() string {
+	return "json"
+}
+
+func (jsonReporter) Extension() string {
+	return "json"
+}
+
+func (jsonReporter) Generate(sum *parserpkg.Summary) ([]byte, error) {
+	return buildJSON(sum)
+}
+
+// buildJSON renders the summary as a JSON document
+func buildJSON(sum *parserpkg.Summary) ([]byte, error) {
+	rep := jsonReport{
+		SchemaVersion: JSONSchemaVersion,
+		GeneratedAt:   time.Now(),
+		Passed:        sum.IsPassed(),
+		Counts: jsonCounts{
+			Packages:        jsonCount(sum.Packages),
+			Tests:           jsonCount(sum.Tests),
+			Subtests:        jsonCount(sum.Subtests),
+			PackageFailures: sum.PackageFailures,
+			BuildFailures:   sum.BuildFailures,
+		},
+		Packages: []jsonPackage{},
+	}
+
+	if total, ok := sum.TotalCoverage(); ok {
+		rep.Coverage = &total
+	}
+
+	for _, pkg := range sum.PackageResults {
+		rep.Packages = append(rep.Packages, buildJSONPackage(pkg))
+	}
+
+	content, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+// buildJSONPackage converts the package result into its JSON representation
+func buildJSONPackage(pkg *parserpkg.PackageResult) jsonPackage {
+	p := jsonPackage{
+		Name:      pkg.PackageName,
+		Status:    string(parserpkg.StatusPass),
+		StartTime: pkg.StartTime,
+		EndTime:   pkg.EndTime,
+		Elapsed:   pkg.ElapsedTime,
+		Coverage:  pkg.Coverage,
+		Output:    nonNilOutput(pkg.Output),
+		Tests:     []jsonTest{},
+	}
+	if !pkg.IsPassed {
+		p.Status = string(parserpkg.StatusFail)
+		if !hasFailedTests(pkg) {
+			p.FailureMessage = packageFailureMessage(pkg)
+			p.Panic = buildJSONPanic(pkg.Panic)
+		}
+		for _, d := range pkg.Diagnostics {
+			p.Diagnostics = append(p.Diagnostics, jsonDiagnostic(d))
+		}
+	}
+
+	for _, test := range pkg.TestResults {
+		p.Tests = append(p.Tests, buildJSONTest(test.TestName, test))
+	}
+
+	return p
+}
+
+// buildJSONTest converts the test result and its subtests into their JSON representation
+func buildJSONTest(fullName string, test *parserpkg.TestResult) jsonTest {
+	t := jsonTest{
+		Name:       test.TestName,
+		FullName:   fullName,
+		Status:     string(test.Status),
+		StartTime:  test.StartTime,
+		EndTime:    test.EndTime,
+		Elapsed:    test.ElapsedTime,
+		SkipReason: test.SkipReason,
+		Output:     nonNilOutput(test.Output),
+		Subtests:   []jsonTest{},
+	}
+	if test.Status == parserpkg.StatusFail {
+		t.FailureMessage = testFailureMessage(test)
+		t.Panic = buildJSONPanic(test.Panic)
+		for _, f := range test.Failures {
+			t.Failures = append(t.Failures, jsonFailure{
+				File:     f.File,
+				Line:     f.Line,
+				Message:  f.Message,
+				Kind:     string(f.Kind),
+				Expected: f.Expected,
+				Actual:   f.Actual,
+				Diff:     f.Diff,
+			})
+		}
+	}
+
+	for _, subtest := range test.Subtests {
+		t.Subtests = append(t.Subtests, buildJSONTest(fullName+"/"+subtest.TestName, subtest))
+	}
+
+	return t
+}
+
+// buildJSONPanic converts the panic into its JSON representation, nil if there is none
+func buildJSONPanic(p *parserpkg.Panic) *jsonPanic {
+	if p == nil {
+		return nil
+	}
+
+	jp := &jsonPanic{Message: p.Message, Timeout: p.Timeout, Stack: []jsonFrame{}}
+	for _, frame := range p.Stack {
+		jp.Stack = append(jp.Stack, jsonFrame(frame))
+	}
+	return jp
+}
+
+// nonNilOutput makes sure the output is encoded as an empty array instead of null
+func nonNilOutput(output []string) []string {
+	if output == nil {
+		return []string{}
+	}
+	return output
+}
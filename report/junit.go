@@ -0,0 +1,205 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the results of a single package
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemOut *junitOutput    `xml:"system-out,omitempty"`
+}
+
+// junitTestCase holds the result of a test or subtest
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+// junitMessage is the body of a failure, error or skipped element
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",cdata"`
+}
+
+// junitOutput is the raw output of a test or package
+type junitOutput struct {
+	Body string `xml:",cdata"`
+}
+
+// junitPackageTestName is the name of the test case reporting a package
+// failure that happened outside of any test
+const junitPackageTestName = "(package)"
+
+func init() {
+	Register(junitReporter{})
+}
+
+// junitReporter renders the summary as JUnit XML for CI test dashboards
+type junitReporter struct{}
+
+func (junitReporter) Format() string {
+	return "junit"
+}
+
+func (junitReporter) Extension() string {
+	return "junit.xml"
+}
+
+func (junitReporter) Generate(sum *parserpkg.Summary) ([]byte, error) {
+	return buildJUnit(sum)
+}
+
+// buildJUnit renders the summary as a JUnit XML document
+func buildJUnit(sum *parserpkg.Summary) ([]byte, error) {
+	suites := junitTestSuites{Name: "trep"}
+
+	var totalTime float64
+	for _, pkg := range sum.PackageResults {
+		suite := buildJUnitSuite(pkg)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures + suite.Errors
+		suites.Skipped += suite.Skipped
+		totalTime += pkg.ElapsedTime
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = formatJUnitTime(totalTime)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// buildJUnitSuite converts the package result into a test suite, flattening
+// subtests into test cases named after their full path
+func buildJUnitSuite(pkg *parserpkg.PackageResult) junitTestSuite {
+	suite := junitTestSuite{
+		Name: pkg.PackageName,
+		Time: formatJUnitTime(pkg.ElapsedTime),
+	}
+	if !pkg.StartTime.IsZero() {
+		suite.Timestamp = pkg.StartTime.Format("2006-01-02T15:04:05")
+	}
+
+	var addTest func(name string, test *parserpkg.TestResult)
+	addTest = func(name string, test *parserpkg.TestResult) {
+		tc := junitTestCase{
+			ClassName: pkg.PackageName,
+			Name:      name,
+			Time:      formatJUnitTime(test.ElapsedTime),
+			SystemOut: newJUnitOutput(test.Output),
+		}
+
+		switch test.Status {
+		case parserpkg.StatusFail:
+			suite.Failures++
+			message := sanitizeXML(testFailureMessage(test))
+			if message == "" {
+				message = "test failed"
+			}
+			body := message
+			if test.Panic != nil {
+				body += "\n\n" + sanitizeXML(panicStack(test.Panic))
+			}
+			tc.Failure = &junitMessage{Message: firstLine(message), Type: "failure", Body: body}
+		case parserpkg.StatusSkip:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: test.SkipReason}
+		}
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, tc)
+
+		for _, subtest := range test.Subtests {
+			addTest(name+"/"+subtest.TestName, subtest)
+		}
+	}
+
+	for _, test := range pkg.TestResults {
+		addTest(test.TestName, test)
+	}
+
+	if !pkg.IsPassed && !hasFailedTests(pkg) {
+		message := sanitizeXML(packageFailureMessage(pkg))
+		suite.Errors++
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			ClassName: pkg.PackageName,
+			Name:      junitPackageTestName,
+			Time:      formatJUnitTime(pkg.ElapsedTime),
+			Error:     &junitMessage{Message: firstLine(message), Type: "error", Body: message},
+		})
+	}
+	suite.SystemOut = newJUnitOutput(pkg.Output)
+
+	return suite
+}
+
+// newJUnitOutput returns the system-out element for the given output, or nil if it is empty
+func newJUnitOutput(output []string) *junitOutput {
+	if len(output) == 0 {
+		return nil
+	}
+	return &junitOutput{Body: sanitizeXML(strings.Join(output, ""))}
+}
+
+// sanitizeXML replaces the characters that are not allowed in XML 1.0 documents,
+// such as the escape sequences of colored output
+func sanitizeXML(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' ||
+			(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF) {
+			return r
+		}
+		return '\uFFFD'
+	}, s)
+}
+
+// formatJUnitTime formats the elapsed seconds the way JUnit consumers expect
+func formatJUnitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// firstLine returns the first line of the given text
+func firstLine(text string) string {
+	if i := strings.Index(text, "\n"); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
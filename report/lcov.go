@@ -0,0 +1,77 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+
+	coveragepkg "github.com/cjp2600/trep/coverage"
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+func init() {
+	Register(lcovReporter{})
+}
+
+// lcovReporter exports the coverage profile as an LCOV tracefile
+type lcovReporter struct{}
+
+func (lcovReporter) Format() string {
+	return "lcov"
+}
+
+func (lcovReporter) Extension() string {
+	return "lcov.info"
+}
+
+func (lcovReporter) exportsProfile() {}
+
+// Generate converts the coverage profile to the LCOV format. Source files are
+// referenced by their path on disk when it is known.
+func (lcovReporter) Generate(sum *parserpkg.Summary) ([]byte, error) {
+	var b bytes.Buffer
+	for _, f := range profileFiles(sum) {
+		filename := f.Path
+		if filename == "" {
+			filename = f.Name
+		}
+
+		b.WriteString("TN:\n")
+		fmt.Fprintf(&b, "SF:%s\n", filename)
+
+		hits := f.LineHits()
+
+		functionsHit := 0
+		for _, fn := range f.Functions {
+			fmt.Fprintf(&b, "FN:%d,%s\n", fn.StartLine, fn.Name)
+		}
+		for _, fn := range f.Functions {
+			count := functionHits(f, fn)
+			if count > 0 {
+				functionsHit++
+			}
+			fmt.Fprintf(&b, "FNDA:%d,%s\n", count, fn.Name)
+		}
+		fmt.Fprintf(&b, "FNF:%d\nFNH:%d\n", len(f.Functions), functionsHit)
+
+		for _, n := range coveragepkg.SortedLines(hits) {
+			fmt.Fprintf(&b, "DA:%d,%d\n", n, hits[n])
+		}
+		valid, covered := countLines(hits)
+		fmt.Fprintf(&b, "LF:%d\nLH:%d\n", valid, covered)
+		b.WriteString("end_of_record\n")
+	}
+
+	return b.Bytes(), nil
+}
+
+// functionHits returns how many times the function was entered, approximated
+// by the highest count of the blocks it contains
+func functionHits(f *coveragepkg.File, fn *coveragepkg.Function) int {
+	count := 0
+	for _, block := range f.Blocks {
+		if block.StartLine >= fn.StartLine && block.EndLine <= fn.EndLine && block.Count > count {
+			count = block.Count
+		}
+	}
+	return count
+}
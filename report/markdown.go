@@ -0,0 +1,110 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+func init() {
+	Register(markdownReporter{})
+}
+
+// markdownReporter renders the summary as Markdown, e.g. for pull request comments
+type markdownReporter struct{}
+
+func (markdownReporter) Format() string {
+	return "markdown"
+}
+
+func (markdownReporter) Extension() string {
+	return "md"
+}
+
+func (markdownReporter) Generate(sum *parserpkg.Summary) ([]byte, error) {
+	var b bytes.Buffer
+
+	status := "✅ PASS"
+	if !sum.IsPassed() {
+		status = "❌ FAIL"
+	}
+	fmt.Fprintf(&b, "# Test Report\n\n")
+	fmt.Fprintf(&b, "**Status:** %s  \n**Generated at:** %s\n\n", status, time.Now().Format("2006-01-02 15:04:05"))
+
+	fmt.Fprintf(&b, "| | Total | Passed | Failed | Skipped |\n|---|---:|---:|---:|---:|\n")
+	writeMarkdownCounts(&b, "Packages", sum.Packages)
+	writeMarkdownCounts(&b, "Tests", sum.Tests)
+	writeMarkdownCounts(&b, "Subtests", sum.Subtests)
+	b.WriteString("\n")
+	if total, ok := sum.TotalCoverage(); ok {
+		fmt.Fprintf(&b, "**Coverage:** %s\n\n", formatPercent(total))
+	}
+
+	var failed, skipped []string
+	for _, pkg := range sum.PackageResults {
+		if !pkg.IsPassed && !hasFailedTests(pkg) {
+			failed = append(failed, formatMarkdownFailure(pkg.PackageName, "", packageFailureMessage(pkg)))
+		}
+		walkTests(pkg, func(fullName string, test *parserpkg.TestResult) {
+			switch test.Status {
+			case parserpkg.StatusFail:
+				failed = append(failed, formatMarkdownFailure(pkg.PackageName, fullName, testFailureMessage(test)))
+			case parserpkg.StatusSkip:
+				item := fmt.Sprintf("- `%s` %s", pkg.PackageName, fullName)
+				if test.SkipReason != "" {
+					item += " — " + escapeMarkdown(test.SkipReason)
+				}
+				skipped = append(skipped, item)
+			}
+		})
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintf(&b, "## Failed Tests\n\n%s\n\n", strings.Join(failed, "\n"))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&b, "## Skipped Tests\n\n%s\n\n", strings.Join(skipped, "\n"))
+	}
+
+	fmt.Fprintf(&b, "## Packages\n\n| Package | Status | Tests | Elapsed | Coverage |\n|---|---|---:|---:|---:|\n")
+	for _, pkg := range sum.PackageResults {
+		pkgStatus := "✅ pass"
+		if !pkg.IsPassed {
+			pkgStatus = "❌ fail"
+		}
+		coverage := "-"
+		if pkg.Coverage != nil {
+			coverage = formatPercent(*pkg.Coverage)
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %d | %.2fs | %s |\n", pkg.PackageName, pkgStatus, len(pkg.TestResults), pkg.ElapsedTime, coverage)
+	}
+
+	return b.Bytes(), nil
+}
+
+// writeMarkdownCounts writes a row of the counters table
+func writeMarkdownCounts(b *bytes.Buffer, name string, c parserpkg.Counts) {
+	fmt.Fprintf(b, "| %s | %d | %d | %d | %d |\n", name, c.Total, c.Passed, c.Failed, c.Skipped)
+}
+
+// formatMarkdownFailure formats a failed test, or a package failure when the
+// test name is empty, as a list item with the failure message in a code block
+func formatMarkdownFailure(pkgName string, testName string, message string) string {
+	item := fmt.Sprintf("- `%s`", pkgName)
+	if testName != "" {
+		item += fmt.Sprintf(" **%s**", testName)
+	}
+	if message != "" {
+		item += "\n  ```\n  " + strings.ReplaceAll(message, "\n", "\n  ") + "\n  ```"
+	}
+	return item
+}
+
+// escapeMarkdown escapes the characters that would break a single line list item
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.NewReplacer("|", "\\|", "*", "\\*", "_", "\\_", "`", "\\`").Replace(s)
+}
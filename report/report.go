@@ -6,101 +6,284 @@ import (
 	"html"
 	"html/template"
 	"io"
-	"io/ioutil"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/cjp2600/trep/extract"
 	parserpkg "github.com/cjp2600/trep/parser"
+	"github.com/cjp2600/trep/source"
 	"github.com/cjp2600/trep/tui"
 	htmlpkg "golang.org/x/net/html"
 )
 
-// GenerateAndSaveReport generates a report and saves it to the given path
-func GenerateAndSaveReport(sum *parserpkg.Summary, reportPath string, reportName string) error {
+func init() {
+	Register(htmlReporter{})
+}
+
+// htmlReporter renders the summary as a standalone HTML page
+type htmlReporter struct{}
+
+func (htmlReporter) Format() string {
+	return "html"
+}
+
+func (htmlReporter) Extension() string {
+	return "html"
+}
+
+// Generate renders the test table as HTML and embeds it into the report page
+func (htmlReporter) Generate(sum *parserpkg.Summary) ([]byte, error) {
 	html, err := captureStdout(func() {
-		tui.BuildTable(sum, tui.WithReportColors()).RenderHTML()
+		tui.BuildTable(sum, tui.WithReportColors(), tui.WithDurations()).RenderHTML()
 	})
 	if err != nil {
-		return fmt.Errorf("error rendering html: %w", err)
+		return nil, fmt.Errorf("error rendering html: %w", err)
 	}
-	return saveReport(html, reportPath, reportName, sum)
+	return renderHTMLReport(html, sum)
 }
 
-// getFailedTests returns a list of failed tests
-func getFailedTests(sum *parserpkg.Summary) []string {
-	var failedTests []string
+// failedTest is a failed test listed in the report
+type failedTest struct {
+	Name      string
+	Locations []failureLocation
+	Diffs     []sideBySideDiff
+}
+
+// failureLocation is the location of a failure, linked to the file on disk
+// and with the surrounding source if it was found
+type failureLocation struct {
+	Text    string
+	URL     template.URL
+	Snippet *source.Snippet
+}
+
+// getFailedTests returns a list of failed tests and subtests at any depth
+// with the locations of their failures
+func getFailedTests(sum *parserpkg.Summary) []failedTest {
+	resolver := source.NewResolver()
+
+	var failedTests []failedTest
 	for _, pkg := range sum.PackageResults {
-		for _, test := range pkg.TestResults {
-			if !test.IsPassed {
-				failedTests = append(failedTests, test.TestName)
+		walkTests(pkg, func(fullName string, test *parserpkg.TestResult) {
+			if test.Status != parserpkg.StatusFail {
+				return
 			}
-			if len(test.Subtests) > 0 {
-				for _, subtest := range test.Subtests {
-					if !subtest.IsPassed {
-						failedTests = append(failedTests, subtest.TestName)
-					}
+
+			ft := failedTest{Name: fullName, Diffs: getSideBySideDiffs(test.Failures)}
+			for _, f := range test.Failures {
+				if f.File == "" {
+					continue
+				}
+				location := failureLocation{Text: fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)}
+				if filename, ok := resolver.PackageFile(pkg.PackageName, f.File); ok {
+					location.URL = template.URL((&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String())
+					location.Snippet, _ = source.ReadSnippet(filename, f.Line, tui.SnippetContext)
 				}
+				ft.Locations = append(ft.Locations, location)
 			}
-		}
+			failedTests = append(failedTests, ft)
+		})
 	}
 	return failedTests
 }
 
-// saveReport saves the report to the given path
-func saveReport(tableHTML string, path string, reportName string, sum *parserpkg.Summary) error {
-	if err := os.MkdirAll(path, os.ModePerm); err != nil {
-		return fmt.Errorf("error creating directories: %w", err)
+// walkTests calls the function for every test and subtest of the package with
+// its full name, in the order of the summary
+func walkTests(pkg *parserpkg.PackageResult, fn func(fullName string, test *parserpkg.TestResult)) {
+	var walk func(fullName string, test *parserpkg.TestResult)
+	walk = func(fullName string, test *parserpkg.TestResult) {
+		fn(fullName, test)
+		for _, subtest := range test.Subtests {
+			walk(fullName+"/"+subtest.TestName, subtest)
+		}
+	}
+
+	for _, test := range pkg.TestResults {
+		walk(test.TestName, test)
+	}
+}
+
+// hasFailedTests reports whether any top-level test of the package failed
+func hasFailedTests(pkg *parserpkg.PackageResult) bool {
+	for _, test := range pkg.TestResults {
+		if test.Status == parserpkg.StatusFail {
+			return true
+		}
 	}
+	return false
+}
 
+// failureMessage extracts the failure message from the given test or package output
+func failureMessage(output []string) string {
+	return extract.Extract(stripFrameLines(output)).Message
+}
+
+// testFailureMessage returns the failure message of the given test
+func testFailureMessage(test *parserpkg.TestResult) string {
+	if test.Panic != nil {
+		return panicMessage(test.Panic)
+	}
+	return failureMessage(test.Output)
+}
+
+// panicMessage returns the message of the panic or timeout
+func panicMessage(p *parserpkg.Panic) string {
+	if p.Timeout {
+		return p.Message
+	}
+	return "panic: " + p.Message
+}
+
+// panicStack formats the stack of the panic the way go prints it
+func panicStack(p *parserpkg.Panic) string {
+	var lines []string
+	for _, frame := range p.Stack {
+		lines = append(lines, frame.Function, fmt.Sprintf("\t%s:%d", frame.File, frame.Line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// packageFailureMessage returns the message of a package that failed outside
+// of its tests, the build errors if the package failed to build
+func packageFailureMessage(pkg *parserpkg.PackageResult) string {
+	if pkg.Panic != nil {
+		return panicMessage(pkg.Panic)
+	}
+	if !pkg.BuildFailed {
+		return failureMessage(pkg.Output)
+	}
+	if len(pkg.Diagnostics) == 0 {
+		return "build failed"
+	}
+
+	lines := make([]string, 0, len(pkg.Diagnostics))
+	for _, d := range pkg.Diagnostics {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getBuildFailures returns the packages that failed to build
+func getBuildFailures(sum *parserpkg.Summary) []*parserpkg.PackageResult {
+	var packages []*parserpkg.PackageResult
+	for _, pkg := range sum.PackageResults {
+		if pkg.BuildFailed {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
+
+// stripFrameLines joins the output without the === RUN, --- FAIL and
+// similar lines go test prints around every test
+func stripFrameLines(output []string) string {
+	var lines []string
+	for _, line := range output {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\n"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// skippedTest is a skipped test listed in the report
+type skippedTest struct {
+	Name   string
+	Reason string
+}
+
+// getSkippedTests returns a list of skipped tests and subtests with their skip reasons
+func getSkippedTests(sum *parserpkg.Summary) []skippedTest {
+	var skippedTests []skippedTest
+	for _, pkg := range sum.PackageResults {
+		walkTests(pkg, func(fullName string, test *parserpkg.TestResult) {
+			if test.Status == parserpkg.StatusSkip {
+				skippedTests = append(skippedTests, skippedTest{Name: fullName, Reason: test.SkipReason})
+			}
+		})
+	}
+	return skippedTests
+}
+
+// packageCoverage is the coverage of a package listed in the report
+type packageCoverage struct {
+	Name     string
+	Coverage string
+}
+
+// getPackageCoverage returns the coverage of the packages that reported one
+func getPackageCoverage(sum *parserpkg.Summary) []packageCoverage {
+	var coverage []packageCoverage
+	for _, pkg := range sum.PackageResults {
+		if pkg.Coverage != nil {
+			coverage = append(coverage, packageCoverage{Name: pkg.PackageName, Coverage: formatPercent(*pkg.Coverage)})
+		}
+	}
+	return coverage
+}
+
+// formatPercent formats the coverage percentage
+func formatPercent(coverage float64) string {
+	return fmt.Sprintf("%.1f%%", coverage)
+}
+
+// renderHTMLReport renders the report page around the given HTML table
+func renderHTMLReport(tableHTML string, sum *parserpkg.Summary) ([]byte, error) {
 	tableHTML = insertRowIDs(tableHTML)
 
 	timestamp := time.Now().Format("20060102_150405")
 
 	type ReportData struct {
-		ReportName  string
-		Table       template.HTML
-		Total       int
-		Passed      int
-		Failed      int
-		IsPassed    bool
-		GeneratedAt string
-		FailedTests []string
+		ReportName        string
+		Table             template.HTML
+		Packages          parserpkg.Counts
+		Tests             parserpkg.Counts
+		Subtests          parserpkg.Counts
+		PackageFailures   int
+		BuildFailures     []*parserpkg.PackageResult
+		IsPassed          bool
+		GeneratedAt       string
+		FailedTests       []failedTest
+		SkippedTests      []skippedTest
+		Coverage          []packageCoverage
+		TotalCoverage     string
+		CoverageFiles     []coverageFile
+		CoverageFunctions []coverageFunction
 	}
 
 	t := template.Must(template.New("report").Parse(reportTemplate))
 
 	data := ReportData{
-		ReportName:  fmt.Sprintf("Report %s", timestamp),
-		Table:       template.HTML(html.UnescapeString(tableHTML)),
-		Total:       sum.TotalPackages,
-		Passed:      sum.TotalPassed,
-		Failed:      sum.TotalFailed,
-		IsPassed:    sum.TotalFailed == 0,
-		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
-		FailedTests: getFailedTests(sum),
+		ReportName:        fmt.Sprintf("Report %s", timestamp),
+		Table:             template.HTML(html.UnescapeString(tableHTML)),
+		Packages:          sum.Packages,
+		Tests:             sum.Tests,
+		Subtests:          sum.Subtests,
+		PackageFailures:   sum.PackageFailures,
+		BuildFailures:     getBuildFailures(sum),
+		IsPassed:          sum.IsPassed(),
+		GeneratedAt:       time.Now().Format("2006-01-02 15:04:05"),
+		FailedTests:       getFailedTests(sum),
+		SkippedTests:      getSkippedTests(sum),
+		Coverage:          getPackageCoverage(sum),
+		CoverageFiles:     getCoverageFiles(sum.Profile),
+		CoverageFunctions: getCoverageFunctions(sum.Profile),
+	}
+	if total, ok := sum.TotalCoverage(); ok {
+		data.TotalCoverage = formatPercent(total)
 	}
 
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, data); err != nil {
-		return fmt.Errorf("error executing template: %w", err)
-	}
-
-	var rep string
-	if reportName != "" {
-		rep = reportName
-	} else {
-		rep = "report_" + timestamp
-	}
-
-	filename := fmt.Sprintf("%s/%s.html", path, rep) // Использование path здесь
-
-	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
-		return fmt.Errorf("error writing to file: %w", err)
+		return nil, fmt.Errorf("error executing template: %w", err)
 	}
 
-	fmt.Printf("Report saved to %s\n", filename)
-	return nil
+	return buf.Bytes(), nil
 }
 
 // captureStdout captures the stdout of the given function
@@ -236,6 +419,9 @@ var reportTemplate = `<html>
   .fg-green {
       color: #3c763d;
   }
+  .fg-yellow {
+      color: #8a6d3b;
+  }
   .summary {
     border: 1px solid #ddd;
     border-radius: 4px;
@@ -296,6 +482,196 @@ var reportTemplate = `<html>
   text-decoration: underline;
 }
 
+.failed-tests .location {
+  font-family: monospace;
+  color: #777;
+}
+
+.failed-tests .location a {
+  color: #337ab7;
+}
+
+.snippet {
+  font-family: monospace;
+  font-size: 12px;
+  background-color: #f8f8f8;
+  border: 1px solid #eee;
+  padding: 5px 0;
+  margin: 5px 0 0 0;
+  overflow-x: auto;
+  tab-size: 4;
+}
+
+.snippet .line {
+  display: block;
+  white-space: pre;
+}
+
+.snippet .line-number {
+  display: inline-block;
+  width: 50px;
+  padding-right: 10px;
+  text-align: right;
+  color: #999;
+  user-select: none;
+}
+
+.snippet .highlight {
+  background-color: #f2dede;
+}
+
+.diff {
+  width: 100%;
+  border-collapse: collapse;
+  table-layout: fixed;
+  font-family: monospace;
+  font-size: 12px;
+  margin: 5px 0 0 0;
+  border: 1px solid #eee;
+}
+
+.diff caption {
+  text-align: left;
+  color: #777;
+  padding-bottom: 3px;
+}
+
+.diff th {
+  background-color: #f8f8f8;
+  text-align: left;
+  padding: 3px 5px;
+}
+
+.diff td {
+  white-space: pre;
+  overflow-x: auto;
+  vertical-align: top;
+  padding: 0 5px;
+  tab-size: 4;
+}
+
+.diff .removed {
+  background-color: #f2dede;
+}
+
+.diff .added {
+  background-color: #dff0d8;
+}
+
+.diff .hunk {
+  color: #31708f;
+  background-color: #eef6fb;
+}
+
+.skipped-tests {
+  border: 1px solid #ddd;
+  border-radius: 4px;
+  padding: 15px;
+  background-color: #fdfdfd;
+  margin-top: 20px;
+  margin-bottom: 20px;
+  box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1);
+}
+
+.skipped-tests h3 {
+  margin-top: 0;
+  color: #8a6d3b;
+}
+
+.skipped-tests ul {
+  list-style-type: none;
+  padding-left: 0;
+}
+
+.skipped-tests ul li {
+  margin-bottom: 10px;
+}
+
+.skipped-tests ul li a {
+  color: black;
+  text-decoration: none;
+}
+
+.skipped-tests .reason {
+  color: #777;
+}
+
+.build-failures {
+  border: 1px solid #ddd;
+  border-radius: 4px;
+  padding: 15px;
+  background-color: #fdfdfd;
+  margin-top: 20px;
+  margin-bottom: 20px;
+  box-shadow: 0 2px 4px rgba(0, 0, 0, 0.1);
+}
+
+.build-failures h3 {
+  margin-top: 0;
+  color: #a94442;
+}
+
+.build-failures .location {
+  font-family: monospace;
+  white-space: nowrap;
+}
+
+.build-failures .message {
+  font-family: monospace;
+  white-space: pre-wrap;
+}
+
+.coverage {
+  margin-top: 20px;
+}
+
+.sortable th {
+  cursor: pointer;
+}
+
+.sortable th:after {
+  content: " \2195";
+  color: #aaa;
+}
+
+.source summary {
+  cursor: pointer;
+  padding: 4px 0;
+}
+
+.source pre {
+  font-family: Menlo, Monaco, Consolas, "Courier New", monospace;
+  font-size: 12px;
+  background-color: #fafafa;
+  border: 1px solid #ddd;
+  padding: 8px 0;
+  overflow-x: auto;
+}
+
+.source .line {
+  display: block;
+  padding: 0 8px;
+  white-space: pre;
+}
+
+.source .line-number {
+  display: inline-block;
+  width: 48px;
+  color: #999;
+  user-select: none;
+}
+
+.source .covered {
+  background-color: #dff0d8;
+}
+
+.source .uncovered {
+  background-color: #f2dede;
+}
+
+.source .partial {
+  background-color: #fcf8e3;
+}
 </style>
 </head>
 <body>
@@ -307,17 +683,37 @@ var reportTemplate = `<html>
       <td>{{ .GeneratedAt }}</td>
     </tr>
     <tr>
-      <th>Total:</th>
-      <td>{{ .Total }}</td>
+      <th>Packages:</th>
+      <td>{{ .Packages.Total }} total, {{ .Packages.Passed }} passed, {{ .Packages.Failed }} failed, {{ .Packages.Skipped }} without tests</td>
+    </tr>
+    <tr>
+      <th>Tests:</th>
+      <td>{{ .Tests.Total }} total, {{ .Tests.Passed }} passed, {{ .Tests.Failed }} failed, {{ .Tests.Skipped }} skipped</td>
+    </tr>
+    {{ if .Subtests.Total }}
+    <tr>
+      <th>Subtests:</th>
+      <td>{{ .Subtests.Total }} total, {{ .Subtests.Passed }} passed, {{ .Subtests.Failed }} failed, {{ .Subtests.Skipped }} skipped</td>
+    </tr>
+    {{ end }}
+    {{ if .BuildFailures }}
+    <tr>
+      <th>Build failures:</th>
+      <td class="fg-red">{{ len .BuildFailures }} package(s) failed to build</td>
     </tr>
+    {{ end }}
+    {{ if .PackageFailures }}
     <tr>
-      <th>Passed:</th>
-      <td>{{ .Passed }}</td>
+      <th>Package failures:</th>
+      <td class="fg-red">{{ .PackageFailures }} package(s) failed outside of tests</td>
     </tr>
+    {{ end }}
+    {{ if .TotalCoverage }}
     <tr>
-      <th>Failed:</th>
-      <td>{{ .Failed }}</td>
+      <th>Coverage:</th>
+      <td>{{ .TotalCoverage }}</td>
     </tr>
+    {{ end }}
     <tr>
       <th>Status:</th>
       <td>
@@ -336,11 +732,152 @@ var reportTemplate = `<html>
   <h3>Failed Tests</h3>
   <ul id="failedTestsList">
     {{ range .FailedTests }}
-      <li><a href="#{{ . }}" style="color: black;">{{ . }}</a></li>
+      <li><a href="#{{ .Name }}" style="color: black;">{{ .Name }}</a>{{ range .Locations }} <span class="location">{{ if .URL }}<a href="{{ .URL }}">{{ .Text }}</a>{{ else }}{{ .Text }}{{ end }}</span>{{ end }}
+        {{ range .Locations }}{{ if .Snippet }}
+        <pre class="snippet">{{ range .Snippet.Lines }}<span class="line{{ if .Highlight }} highlight{{ end }}"><span class="line-number">{{ .Number }}</span>{{ .Text }}</span>
+{{ end }}</pre>
+        {{ end }}{{ end }}
+        {{ range .Diffs }}
+        <table class="diff">
+          <caption>{{ .Title }}</caption>
+          <tr><th>Expected</th><th>Actual</th></tr>
+          {{ range .Rows }}<tr><td class="{{ .LeftClass }}">{{ .Left }}</td><td class="{{ .RightClass }}">{{ .Right }}</td></tr>
+          {{ end }}
+        </table>
+        {{ end }}
+      </li>
+    {{ end }}
+  </ul>
+</div>
+{{ end }}
+{{ if .BuildFailures }}
+<div class="build-failures">
+  <h3>Build Failures</h3>
+  {{ range .BuildFailures }}
+  <h4>{{ .PackageName }}</h4>
+  <table class="go-pretty-table">
+    <thead>
+      <tr><th>Location</th><th>Message</th></tr>
+    </thead>
+    <tbody>
+    {{ range .Diagnostics }}
+      <tr><td class="location">{{ .Location }}</td><td class="message">{{ .Message }}</td></tr>
+    {{ else }}
+      <tr><td class="location"></td><td class="message">build failed</td></tr>
+    {{ end }}
+    </tbody>
+  </table>
+  {{ end }}
+</div>
+{{ end }}
+{{ if .SkippedTests }}
+<div class="skipped-tests">
+  <h3>Skipped Tests</h3>
+  <ul id="skippedTestsList">
+    {{ range .SkippedTests }}
+      <li><a href="#{{ .Name }}">{{ .Name }}</a>{{ if .Reason }} <span class="reason">&mdash; {{ .Reason }}</span>{{ end }}</li>
     {{ end }}
   </ul>
 </div>
 {{ end }}
 {{ .Table }}
+{{ if .Coverage }}
+<div class="coverage">
+  <h3>Coverage</h3>
+  <table class="go-pretty-table">
+    <thead>
+      <tr><th>Package</th><th>Coverage</th></tr>
+    </thead>
+    <tbody>
+    {{ range .Coverage }}
+      <tr><td>{{ .Name }}</td><td>{{ .Coverage }}</td></tr>
+    {{ end }}
+    </tbody>
+    <tfoot>
+      <tr><th>Total</th><th>{{ .TotalCoverage }}</th></tr>
+    </tfoot>
+  </table>
+</div>
+{{ end }}
+{{ if .CoverageFiles }}
+<div class="coverage">
+  <h3>Files</h3>
+  <table class="go-pretty-table sortable">
+    <thead>
+      <tr><th>File</th><th data-type="number">Statements</th><th data-type="number">Covered</th><th data-type="number">Coverage</th></tr>
+    </thead>
+    <tbody>
+    {{ range .CoverageFiles }}
+      <tr>
+        <td>{{ if .Lines }}<a href="#{{ .ID }}">{{ .Name }}</a>{{ else }}{{ .Name }}{{ end }}</td>
+        <td>{{ .Statements }}</td>
+        <td>{{ .Covered }}</td>
+        <td data-sort="{{ .Percent }}">{{ printf "%.1f%%" .Percent }}</td>
+      </tr>
+    {{ end }}
+    </tbody>
+  </table>
+</div>
+{{ if .CoverageFunctions }}
+<div class="coverage">
+  <h3>Functions</h3>
+  <table class="go-pretty-table sortable">
+    <thead>
+      <tr><th>File</th><th>Function</th><th data-type="number">Line</th><th data-type="number">Statements</th><th data-type="number">Coverage</th></tr>
+    </thead>
+    <tbody>
+    {{ range .CoverageFunctions }}
+      <tr>
+        <td><a href="#{{ .FileID }}">{{ .File }}</a></td>
+        <td>{{ .Name }}</td>
+        <td>{{ .Line }}</td>
+        <td>{{ .Statements }}</td>
+        <td data-sort="{{ .Percent }}">{{ printf "%.1f%%" .Percent }}</td>
+      </tr>
+    {{ end }}
+    </tbody>
+  </table>
+</div>
+{{ end }}
+<div class="coverage">
+  <h3>Source</h3>
+  {{ range .CoverageFiles }}{{ if .Lines }}
+  <details class="source" id="{{ .ID }}">
+    <summary>{{ .Name }} ({{ printf "%.1f%%" .Percent }})</summary>
+    <pre>{{ range .Lines }}<span class="line {{ .Class }}"><span class="line-number">{{ .Number }}</span>{{ .Text }}</span>{{ end }}</pre>
+  </details>
+  {{ end }}{{ end }}
+</div>
+{{ end }}
+<script>
+  document.querySelectorAll("table.sortable th").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var tbody = th.closest("table").tBodies[0];
+      var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+      var numeric = th.dataset.type === "number";
+      var asc = th.dataset.order !== "asc";
+      th.dataset.order = asc ? "asc" : "desc";
+      var rows = Array.prototype.slice.call(tbody.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[index].dataset.sort || a.cells[index].textContent.trim();
+        var y = b.cells[index].dataset.sort || b.cells[index].textContent.trim();
+        var result = numeric ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+        return asc ? result : -result;
+      });
+      rows.forEach(function (row) {
+        tbody.appendChild(row);
+      });
+    });
+  });
+
+  function openSource() {
+    var el = document.getElementById(location.hash.slice(1));
+    if (el && el.tagName === "DETAILS") {
+      el.open = true;
+    }
+  }
+  window.addEventListener("hashchange", openSource);
+  openSource();
+</script>
 </body>
 </html>`
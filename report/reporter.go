@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+// Reporter renders the summary in a single report format
+type Reporter interface {
+	// Format returns the name the reporter is selected by, e.g. "html"
+	Format() string
+	// Extension returns the extension of the generated file, without the leading dot
+	Extension() string
+	// Generate renders the summary
+	Generate(sum *parserpkg.Summary) ([]byte, error)
+}
+
+// reporters holds the registered reporters by format
+var reporters = make(map[string]Reporter)
+
+// Register makes the reporter available under its format name, replacing
+// any reporter previously registered for the same format
+func Register(r Reporter) {
+	reporters[r.Format()] = r
+}
+
+// Get returns the reporter registered for the given format
+func Get(format string) (Reporter, bool) {
+	r, ok := reporters[format]
+	return r, ok
+}
+
+// Formats returns the sorted names of all registered formats
+func Formats() []string {
+	formats := make([]string, 0, len(reporters))
+	for format := range reporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// profileReporter is implemented by the reporters that export the coverage
+// profile rather than the results of the tests
+type profileReporter interface {
+	exportsProfile()
+}
+
+// ExportsProfile reports whether the format exports the coverage profile, and
+// so needs one to be collected. Without a profile the report is empty.
+func ExportsProfile(format string) bool {
+	r, ok := Get(strings.TrimSpace(format))
+	if !ok {
+		return false
+	}
+	_, ok = r.(profileReporter)
+	return ok
+}
+
+// ValidateFormats returns an error if any of the given formats is not registered
+func ValidateFormats(formats []string) error {
+	for _, format := range formats {
+		if _, ok := Get(strings.TrimSpace(format)); !ok {
+			return fmt.Errorf("unknown report format %q, available formats: %s", format, strings.Join(Formats(), ", "))
+		}
+	}
+	return nil
+}
+
+// GenerateAndSave generates the report in every given format and saves them
+// to the given path. All files share the same base name: the report name if
+// given, report_<timestamp> otherwise, followed by the reporter extension.
+func GenerateAndSave(sum *parserpkg.Summary, formats []string, reportPath string, reportName string) error {
+	if err := ValidateFormats(formats); err != nil {
+		return err
+	}
+
+	if reportName == "" {
+		reportName = "report_" + time.Now().Format("20060102_150405")
+	}
+
+	for _, format := range formats {
+		r, _ := Get(strings.TrimSpace(format))
+		content, err := r.Generate(sum)
+		if err != nil {
+			return fmt.Errorf("error generating %s report: %w", r.Format(), err)
+		}
+		if err = writeReportFile(reportPath, reportName+"."+r.Extension(), content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeReportFile writes the report content to the given path
+func writeReportFile(path string, filename string, content []byte) error {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating directories: %w", err)
+	}
+
+	filename = filepath.Join(path, filename)
+	if err := ioutil.WriteFile(filename, content, 0644); err != nil {
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+
+	fmt.Printf("Report saved to %s\n", filename)
+	return nil
+}
@@ -0,0 +1,124 @@
+package source
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Resolver maps import paths to directories on disk. Packages of the main
+// module are resolved from its go.mod, any other package through go list.
+type Resolver struct {
+	moduleRoot string
+	modulePath string
+	dirs       map[string]string
+}
+
+// NewResolver creates a resolver for the module containing the working directory
+func NewResolver() *Resolver {
+	r := &Resolver{dirs: make(map[string]string)}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return r
+	}
+
+	for {
+		if modulePath, ok := readModulePath(filepath.Join(dir, "go.mod")); ok {
+			r.moduleRoot = dir
+			r.modulePath = modulePath
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return r
+}
+
+// PackageDir returns the directory of the package with the given import path
+func (r *Resolver) PackageDir(importPath string) (string, bool) {
+	if dir, ok := r.dirs[importPath]; ok {
+		return dir, dir != ""
+	}
+
+	dir := r.lookupDir(importPath)
+	r.dirs[importPath] = dir
+	return dir, dir != ""
+}
+
+// File returns the path on disk of a file named after its package import
+// path, e.g. github.com/user/project/pkg/file.go
+func (r *Resolver) File(name string) (string, bool) {
+	dir, ok := r.PackageDir(path.Dir(name))
+	if !ok {
+		return "", false
+	}
+
+	filename := filepath.Join(dir, path.Base(name))
+	if _, err := os.Stat(filename); err != nil {
+		return "", false
+	}
+	return filename, true
+}
+
+// PackageFile returns the path on disk of a file as go test prints it in the
+// output of a package, either absolute or relative to the package directory
+func (r *Resolver) PackageFile(importPath string, name string) (string, bool) {
+	filename := name
+	if !filepath.IsAbs(filename) {
+		dir, ok := r.PackageDir(importPath)
+		if !ok {
+			return "", false
+		}
+		filename = filepath.Join(dir, filepath.FromSlash(name))
+	}
+
+	if _, err := os.Stat(filename); err != nil {
+		return "", false
+	}
+	return filename, true
+}
+
+// lookupDir finds the directory of the package, returning an empty string if it is unknown
+func (r *Resolver) lookupDir(importPath string) string {
+	if r.modulePath != "" {
+		if importPath == r.modulePath {
+			return r.moduleRoot
+		}
+		if strings.HasPrefix(importPath, r.modulePath+"/") {
+			return filepath.Join(r.moduleRoot, filepath.FromSlash(strings.TrimPrefix(importPath, r.modulePath+"/")))
+		}
+	}
+
+	out, err := exec.Command("go", "list", "-f", "{{.Dir}}", importPath).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// readModulePath returns the module path declared in the given go.mod file
+func readModulePath(filename string) (string, bool) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,44 @@
+package source
+
+import (
+	"bufio"
+	"os"
+)
+
+// Snippet is a range of source lines around a line of interest
+type Snippet struct {
+	File  string
+	Lines []SnippetLine
+}
+
+// SnippetLine is a line of a snippet
+type SnippetLine struct {
+	Number    int
+	Text      string
+	Highlight bool // the line of interest
+}
+
+// ReadSnippet reads the given line of the file with up to context lines
+// before and after it
+func ReadSnippet(filename string, line int, context int) (*Snippet, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snippet := &Snippet{File: filename}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 1024*1024)
+	for n := 1; scanner.Scan() && n <= line+context; n++ {
+		if n < line-context {
+			continue
+		}
+		snippet.Lines = append(snippet.Lines, SnippetLine{Number: n, Text: scanner.Text(), Highlight: n == line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippet, nil
+}
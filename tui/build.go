@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"os"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	tablepkg "github.com/jedib0t/go-pretty/v6/table"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+// HasBuildFailures reports whether any package of the summary failed to build
+func HasBuildFailures(sum *parserpkg.Summary) bool {
+	return sum.BuildFailures > 0
+}
+
+// BuildDiagnosticsTable builds a table with the build errors grouped by the
+// package that failed to build
+func BuildDiagnosticsTable(sum *parserpkg.Summary, opts ...RenderOptionFunc) tablepkg.Writer {
+	options := &renderOption{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	t := tablepkg.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle("Build failures")
+	t.AppendHeader(tablepkg.Row{"Location", "Message"})
+
+	for _, pkg := range sum.PackageResults {
+		if !pkg.BuildFailed {
+			continue
+		}
+
+		t.AppendRow(tablepkg.Row{formatWithColor(pkg.PackageName, textpkg.FgRed, options.ReportColors(), true), ""})
+		for _, d := range pkg.Diagnostics {
+			t.AppendRow(tablepkg.Row{d.Location(), d.Message})
+		}
+		t.AppendSeparator()
+	}
+
+	t.SetStyle(tablepkg.StyleLight)
+	return t
+}
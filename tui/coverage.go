@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	tablepkg "github.com/jedib0t/go-pretty/v6/table"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+// HasCoverage reports whether any package of the summary reported coverage
+func HasCoverage(sum *parserpkg.Summary) bool {
+	_, ok := sum.TotalCoverage()
+	return ok
+}
+
+// BuildCoverageTable builds a table with the coverage of every package and the total coverage
+func BuildCoverageTable(sum *parserpkg.Summary, opts ...RenderOptionFunc) tablepkg.Writer {
+	options := &renderOption{}
+	for _, o := range opts {
+		o(options)
+	}
+
+	t := tablepkg.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(tablepkg.Row{"Package", "Coverage"})
+
+	for _, pkg := range sum.PackageResults {
+		if pkg.Coverage == nil {
+			continue
+		}
+		t.AppendRow(tablepkg.Row{pkg.PackageName, formatCoverage(*pkg.Coverage, options)})
+	}
+
+	if total, ok := sum.TotalCoverage(); ok {
+		t.AppendFooter(tablepkg.Row{"Total", formatCoverage(total, options)})
+	}
+
+	t.SetStyle(tablepkg.StyleLight)
+	return t
+}
+
+// formatCoverage formats the coverage percentage, colored red if it is below the minimum
+func formatCoverage(coverage float64, options *renderOption) string {
+	color := textpkg.FgGreen
+	if options.minCoverage > 0 && coverage < options.minCoverage {
+		color = textpkg.FgRed
+	}
+	return formatWithColor(fmt.Sprintf("%.1f%%", coverage), color, options.ReportColors(), false)
+}
@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"strings"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+// hasComparison reports whether any of the failures compares an expected and an actual value
+func hasComparison(failures []parserpkg.Failure) bool {
+	for _, f := range failures {
+		if isComparison(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComparison reports whether the failure compares an expected and an actual value
+func isComparison(f parserpkg.Failure) bool {
+	return len(f.Diff) > 0 || f.Expected != "" || f.Actual != ""
+}
+
+// formatComparisons formats the failures of a test, rendering the compared
+// values as a colored unified diff. Unlike formatCompactOutput it keeps the
+// whitespace of the values, only tabs are expanded to keep the table aligned.
+func formatComparisons(failures []parserpkg.Failure, reportColors bool) string {
+	var lines []string
+	for _, f := range failures {
+		if !isComparison(f) {
+			lines = append(lines, formatCompactOutput(f.Message))
+			continue
+		}
+
+		lines = append(lines, strings.TrimSpace(strings.SplitN(f.Message, "\n", 2)[0]))
+		if len(f.Diff) == 0 {
+			lines = append(lines,
+				formatWithColor(expandTabs("- "+f.Expected), textpkg.FgRed, reportColors, false),
+				formatWithColor(expandTabs("+ "+f.Actual), textpkg.FgGreen, reportColors, false))
+			continue
+		}
+
+		for _, line := range f.Diff {
+			lines = append(lines, formatDiffLine(line, reportColors))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDiffLine colors a line of a unified diff
+func formatDiffLine(line string, reportColors bool) string {
+	line = expandTabs(line)
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return formatWithColor(line, textpkg.FgCyan, reportColors, false)
+	case strings.HasPrefix(line, "-"):
+		return formatWithColor(line, textpkg.FgRed, reportColors, false)
+	case strings.HasPrefix(line, "+"):
+		return formatWithColor(line, textpkg.FgGreen, reportColors, false)
+	}
+	return line
+}
+
+// expandTabs replaces the tabs of the line with spaces
+func expandTabs(line string) string {
+	return strings.ReplaceAll(line, "\t", "    ")
+}
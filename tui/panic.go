@@ -0,0 +1,39 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+// maxStackFrames is the number of stack frames shown for a panic
+const maxStackFrames = 8
+
+// formatPanic formats the panic message and its stack, highlighting the
+// frames of the tested code
+func formatPanic(p *parserpkg.Panic, reportColors bool) string {
+	message := "panic: " + p.Message
+	if p.Timeout {
+		message = p.Message
+	}
+	lines := []string{formatWithColor(message, textpkg.FgRed, reportColors, false)}
+
+	for i, frame := range p.Stack {
+		if i == maxStackFrames {
+			lines = append(lines, formatWithColor(fmt.Sprintf("... %d more", len(p.Stack)-i), textpkg.FgHiBlack, reportColors, false))
+			break
+		}
+
+		location := fmt.Sprintf("%s (%s:%d)", frame.Function, filepath.Base(frame.File), frame.Line)
+		if frame.User {
+			lines = append(lines, formatWithColor(location, textpkg.FgYellow, reportColors, true))
+		} else {
+			lines = append(lines, formatWithColor(location, textpkg.FgHiBlack, reportColors, false))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,214 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+const (
+	// progressRefreshInterval is how often the status area is redrawn
+	progressRefreshInterval = 100 * time.Millisecond
+	// progressMaxLines limits the number of running packages and tests listed
+	progressMaxLines = 20
+	// progressWidth is the width the status lines are trimmed to, so that they never wrap
+	progressWidth = 120
+)
+
+// Progress renders a live multi-line status area with the packages and tests
+// that are currently running and counters of the finished tests. It is fed
+// by subscribing Handle to the parser.
+type Progress struct {
+	mu  sync.Mutex
+	out io.Writer
+
+	startedAt time.Time
+	packages  map[string]*runningItem
+	tests     map[string]map[string]*runningItem
+	passed    int
+	failed    int
+	skipped   int
+
+	drawnLines int
+	frame      int
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+}
+
+// runningItem is a package or a test that has started but not finished yet
+type runningItem struct {
+	name      string
+	startedAt time.Time
+	paused    bool // a parallel test waiting for the tests that are not parallel
+}
+
+// NewProgress creates a progress view writing to the given output
+func NewProgress(out io.Writer) *Progress {
+	return &Progress{
+		out:      out,
+		packages: make(map[string]*runningItem),
+		tests:    make(map[string]map[string]*runningItem),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Handle updates the view with the given parser event
+func (p *Progress) Handle(event *parserpkg.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pkgName := event.Action.Package
+	switch event.Type {
+	case parserpkg.EventPackageStart:
+		p.startPackage(pkgName)
+	case parserpkg.EventPackageEnd, parserpkg.EventPackageSkip:
+		delete(p.packages, pkgName)
+		delete(p.tests, pkgName)
+	case parserpkg.EventTestRun:
+		p.startPackage(pkgName)
+		p.tests[pkgName][event.Action.Test] = &runningItem{name: event.Action.Test, startedAt: time.Now()}
+	case parserpkg.EventTestPause, parserpkg.EventTestCont:
+		if test, ok := p.tests[pkgName][event.Action.Test]; ok {
+			test.paused = event.Type == parserpkg.EventTestPause
+		}
+	case parserpkg.EventTestEnd:
+		delete(p.tests[pkgName], event.Action.Test)
+		switch event.Action.Action {
+		case "pass":
+			p.passed++
+		case "fail":
+			p.failed++
+		case "skip":
+			p.skipped++
+		}
+	}
+}
+
+// startPackage registers the package as running if it is not known yet
+func (p *Progress) startPackage(name string) {
+	if _, ok := p.packages[name]; ok {
+		return
+	}
+	p.packages[name] = &runningItem{name: name, startedAt: time.Now()}
+	p.tests[name] = make(map[string]*runningItem)
+}
+
+// Start starts redrawing the status area in the background
+func (p *Progress) Start() {
+	p.startedAt = time.Now()
+
+	go func() {
+		defer close(p.doneCh)
+
+		ticker := time.NewTicker(progressRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			p.draw()
+			select {
+			case <-p.stopCh:
+				p.clear()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the redrawing and removes the status area from the screen
+func (p *Progress) Stop() {
+	close(p.stopCh)
+	<-p.doneCh
+}
+
+// draw replaces the previously drawn status area with the current state
+func (p *Progress) draw() {
+	p.mu.Lock()
+	lines := p.buildLines()
+	p.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString(p.eraseSequence())
+	for _, line := range lines {
+		b.WriteString(textpkg.Trim(line, progressWidth))
+		b.WriteString("\n")
+	}
+	fmt.Fprint(p.out, b.String())
+
+	p.drawnLines = len(lines)
+	p.frame++
+}
+
+// clear removes the status area from the screen
+func (p *Progress) clear() {
+	fmt.Fprint(p.out, p.eraseSequence())
+	p.drawnLines = 0
+}
+
+// eraseSequence returns the escape sequence moving the cursor to the first
+// drawn line and clearing everything below it
+func (p *Progress) eraseSequence() string {
+	if p.drawnLines == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\033[%dF\033[J", p.drawnLines)
+}
+
+// buildLines returns the lines of the status area
+func (p *Progress) buildLines() []string {
+	loaderChars := `-\|/`
+	now := time.Now()
+
+	lines := []string{fmt.Sprintf("%s Running tests... %s  %s  %s  %s",
+		textpkg.FgCyan.Sprint(string(loaderChars[p.frame%len(loaderChars)])),
+		formatElapsed(now.Sub(p.startedAt)),
+		textpkg.FgGreen.Sprintf("✓ %d passed", p.passed),
+		textpkg.FgRed.Sprintf("× %d failed", p.failed),
+		textpkg.FgYellow.Sprintf("⊘ %d skipped", p.skipped),
+	)}
+
+	var running []string
+	for _, pkg := range sortRunning(p.packages) {
+		running = append(running, fmt.Sprintf("  %s %s", textpkg.Bold.Sprint(pkg.name), textpkg.FgHiBlack.Sprint(formatElapsed(now.Sub(pkg.startedAt)))))
+		for _, test := range sortRunning(p.tests[pkg.name]) {
+			if test.paused {
+				continue
+			}
+			running = append(running, fmt.Sprintf("    %s %s", test.name, textpkg.FgHiBlack.Sprint(formatElapsed(now.Sub(test.startedAt)))))
+		}
+	}
+
+	if len(running) > progressMaxLines {
+		hidden := len(running) - progressMaxLines
+		running = append(running[:progressMaxLines], textpkg.FgHiBlack.Sprintf("  ... and %d more", hidden))
+	}
+
+	return append(lines, running...)
+}
+
+// sortRunning returns the items ordered by the time they started
+func sortRunning(items map[string]*runningItem) []*runningItem {
+	sorted := make([]*runningItem, 0, len(items))
+	for _, item := range items {
+		sorted = append(sorted, item)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].startedAt.Equal(sorted[j].startedAt) {
+			return sorted[i].name < sorted[j].name
+		}
+		return sorted[i].startedAt.Before(sorted[j].startedAt)
+	})
+	return sorted
+}
+
+// formatElapsed formats the given duration with a precision suitable for the status area
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
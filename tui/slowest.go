@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"os"
+	"sort"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	tablepkg "github.com/jedib0t/go-pretty/v6/table"
+)
+
+// BuildSlowestTestsTable builds a table with the n slowest top-level tests of the run
+func BuildSlowestTestsTable(sum *parserpkg.Summary, n int) tablepkg.Writer {
+	type slowTest struct {
+		pkg  string
+		test *parserpkg.TestResult
+	}
+
+	var tests []slowTest
+	for _, pkg := range sum.PackageResults {
+		for _, test := range pkg.TestResults {
+			tests = append(tests, slowTest{pkg: pkg.PackageName, test: test})
+		}
+	}
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].test.ElapsedTime > tests[j].test.ElapsedTime
+	})
+	if len(tests) > n {
+		tests = tests[:n]
+	}
+
+	t := tablepkg.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle("Slowest tests")
+	t.AppendHeader(tablepkg.Row{"Test", "Package", "Duration"})
+	for _, st := range tests {
+		t.AppendRow(tablepkg.Row{st.test.TestName, st.pkg, FormatDuration(st.test.ElapsedTime)})
+	}
+
+	t.SetStyle(tablepkg.StyleLight)
+	t.SetAutoIndex(true)
+	return t
+}
+
+// BuildSlowestPackagesTable builds a table with the n slowest packages of the run
+func BuildSlowestPackagesTable(sum *parserpkg.Summary, n int) tablepkg.Writer {
+	packages := make([]*parserpkg.PackageResult, len(sum.PackageResults))
+	copy(packages, sum.PackageResults)
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].ElapsedTime > packages[j].ElapsedTime
+	})
+	if len(packages) > n {
+		packages = packages[:n]
+	}
+
+	t := tablepkg.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.SetTitle("Slowest packages")
+	t.AppendHeader(tablepkg.Row{"Package", "Duration"})
+	for _, pkg := range packages {
+		t.AppendRow(tablepkg.Row{pkg.PackageName, FormatDuration(pkg.ElapsedTime)})
+	}
+
+	t.SetStyle(tablepkg.StyleLight)
+	t.SetAutoIndex(true)
+	return t
+}
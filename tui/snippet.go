@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+	"github.com/cjp2600/trep/source"
+	textpkg "github.com/jedib0t/go-pretty/v6/text"
+)
+
+// SnippetContext is the number of source lines shown before and after a failure
+const SnippetContext = 3
+
+// BuildSnippets returns the source around the location of every failure of
+// the failed tests, with the failing line highlighted
+func BuildSnippets(sum *parserpkg.Summary, resolver *source.Resolver) string {
+	var b strings.Builder
+	for _, pkg := range sum.PackageResults {
+		var walk func(fullName string, test *parserpkg.TestResult)
+		walk = func(fullName string, test *parserpkg.TestResult) {
+			if test.Status == parserpkg.StatusFail {
+				for _, f := range test.Failures {
+					writeSnippet(&b, pkg.PackageName, fullName, f, resolver)
+				}
+			}
+			for _, subtest := range test.Subtests {
+				walk(fullName+"/"+subtest.TestName, subtest)
+			}
+		}
+		for _, test := range pkg.TestResults {
+			walk(test.TestName, test)
+		}
+	}
+	return b.String()
+}
+
+// writeSnippet writes the source around the failure, nothing if the file is not found
+func writeSnippet(b *strings.Builder, pkg string, testName string, f parserpkg.Failure, resolver *source.Resolver) {
+	if f.File == "" {
+		return
+	}
+	filename, ok := resolver.PackageFile(pkg, f.File)
+	if !ok {
+		return
+	}
+	snippet, err := source.ReadSnippet(filename, f.Line, SnippetContext)
+	if err != nil || len(snippet.Lines) == 0 {
+		return
+	}
+
+	width := len(fmt.Sprint(snippet.Lines[len(snippet.Lines)-1].Number))
+	fmt.Fprintf(b, "%s %s %s\n", textpkg.Bold.Sprint(testName), textpkg.FgHiBlack.Sprint(pkg),
+		textpkg.FgYellow.Sprintf("%s:%d", filepath.Base(f.File), f.Line))
+	for _, line := range snippet.Lines {
+		text := strings.ReplaceAll(line.Text, "\t", "    ")
+		if line.Highlight {
+			fmt.Fprintln(b, textpkg.FgRed.Sprintf(" > %*d │ %s", width, line.Number, text))
+			continue
+		}
+		fmt.Fprintf(b, "   %s │ %s\n", textpkg.FgHiBlack.Sprintf("%*d", width, line.Number), text)
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,32 @@
+package tui
+
+import (
+	"fmt"
+
+	parserpkg "github.com/cjp2600/trep/parser"
+)
+
+// BuildSummary returns the footer lines with the package, test and subtest counters
+func BuildSummary(sum *parserpkg.Summary) []string {
+	lines := []string{
+		fmt.Sprintf("packages: %d total, %d passed, %d failed, %d without tests",
+			sum.Packages.Total, sum.Packages.Passed, sum.Packages.Failed, sum.Packages.Skipped),
+		fmt.Sprintf("tests:    %d total, %d passed, %d failed, %d skipped",
+			sum.Tests.Total, sum.Tests.Passed, sum.Tests.Failed, sum.Tests.Skipped),
+	}
+
+	if sum.Subtests.Total > 0 {
+		lines = append(lines, fmt.Sprintf("subtests: %d total, %d passed, %d failed, %d skipped",
+			sum.Subtests.Total, sum.Subtests.Passed, sum.Subtests.Failed, sum.Subtests.Skipped))
+	}
+
+	if sum.BuildFailures > 0 {
+		lines = append(lines, fmt.Sprintf("%d package(s) failed to build", sum.BuildFailures))
+	}
+
+	if sum.PackageFailures > 0 {
+		lines = append(lines, fmt.Sprintf("%d package(s) failed outside of tests (e.g. TestMain or init)", sum.PackageFailures))
+	}
+
+	return lines
+}
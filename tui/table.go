@@ -3,9 +3,10 @@ package tui
 import (
 	"fmt"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strings"
 
+	"github.com/cjp2600/trep/extract"
 	parserpkg "github.com/cjp2600/trep/parser"
 	tablepkg "github.com/jedib0t/go-pretty/v6/table"
 	textpkg "github.com/jedib0t/go-pretty/v6/text"
@@ -18,21 +19,39 @@ func BuildTable(sum *parserpkg.Summary, opts ...RenderOptionFunc) tablepkg.Write
 		o(options)
 	}
 
-	// Check if any test has output
+	isVisible := func(test *parserpkg.TestResult) bool {
+		if options.onlyFail != nil && *options.onlyFail && test.Status != parserpkg.StatusFail {
+			return false
+		}
+		if options.onlyPass != nil && *options.onlyPass && test.Status != parserpkg.StatusPass {
+			return false
+		}
+		return true
+	}
+
+	// Check if any test has output or a failure location
 	hasOutput := false
-	checkOutput := func(test *parserpkg.TestResult) {
-		output := getOutput(test)
+	hasLocation := false
+	var checkOutput func(test *parserpkg.TestResult)
+	checkOutput = func(test *parserpkg.TestResult) {
+		output := getOutput(test, options.ReportColors())
 		if len(output) > 0 {
 			hasOutput = true
 		}
+		if getLocation(test) != "" {
+			hasLocation = true
+		}
+		for _, s := range test.Subtests {
+			checkOutput(s)
+		}
 	}
 
 	for _, tr := range sum.PackageResults {
+		if tr.Panic != nil {
+			hasOutput = true
+		}
 		for _, test := range tr.TestResults {
 			checkOutput(test)
-			for _, s := range test.Subtests {
-				checkOutput(s)
-			}
 		}
 	}
 
@@ -40,50 +59,92 @@ func BuildTable(sum *parserpkg.Summary, opts ...RenderOptionFunc) tablepkg.Write
 	t.SetOutputMirror(os.Stdout)
 
 	headerRows := tablepkg.Row{"Name", "Status"}
+	if options.durations {
+		headerRows = append(headerRows, "Duration")
+	}
+	if hasLocation {
+		headerRows = append(headerRows, "Location")
+	}
 	if !options.ciMode && hasOutput {
 		headerRows = append(headerRows, "Output")
 	}
 	t.AppendHeader(headerRows)
 
-	processTest := func(test *parserpkg.TestResult, isSubtest bool, isLast bool) {
-		if options.onlyFail != nil && *options.onlyFail && test.IsPassed {
-			return
-		}
-		if options.onlyPass != nil && *options.onlyPass && !test.IsPassed {
-			return
+	// processTest appends the row of the test followed by the rows of its
+	// subtests, at any depth; indent is the tree prefix of the ancestors
+	var processTest func(test *parserpkg.TestResult, depth int, indent string, isLast bool)
+	processTest = func(test *parserpkg.TestResult, depth int, indent string, isLast bool) {
+		var isBold = len(test.Subtests) > 0 && depth == 0
+		var testName = formatWithColor(test.TestName, getStatusColor(test.Status), options.ReportColors(), isBold)
+
+		var childIndent string
+		if depth > 0 {
+			testName = indent + getSymbol(isLast, options.ReportColors()) + testName
+			childIndent = indent + getIndent(isLast, options.ReportColors())
 		}
 
-		var testName = test.TestName
-		var isBold = len(test.Subtests) > 0 && !isSubtest
-		if test.IsPassed {
-			testName = formatWithColor(testName, textpkg.FgGreen, options.ReportColors(), isBold)
-		} else {
-			testName = formatWithColor(testName, textpkg.FgRed, options.ReportColors(), isBold)
+		tRows := []tablepkg.Row{
+			{testName, getStatusStr(test.Status, options.ReportColors())},
 		}
 
-		if isSubtest {
-			symbol := getSymbol(isLast, options.ReportColors())
-			testName = symbol + testName
+		if options.durations {
+			tRows[0] = append(tRows[0], FormatDuration(test.ElapsedTime))
 		}
-
-		tRows := []tablepkg.Row{
-			{testName, getIsPassedStr(test.IsPassed, options.ReportColors())},
+		if hasLocation {
+			tRows[0] = append(tRows[0], getLocation(test))
 		}
 
 		if !options.ciMode && hasOutput {
-			tRows[0] = append(tRows[0], getOutput(test))
+			tRows[0] = append(tRows[0], getOutput(test, options.ReportColors()))
 		}
 		t.AppendRows(tRows)
+
+		var subtests []*parserpkg.TestResult
+		for _, s := range test.Subtests {
+			if isVisible(s) {
+				subtests = append(subtests, s)
+			}
+		}
+		for i, s := range subtests {
+			processTest(s, depth+1, childIndent, i == len(subtests)-1)
+		}
 	}
 
 	for _, tr := range sum.PackageResults {
+		var tests []*parserpkg.TestResult
 		for _, test := range tr.TestResults {
-			processTest(test, false, false)
+			if isVisible(test) {
+				tests = append(tests, test)
+			}
+		}
+
+		collapsed := options.collapsePassed && tr.IsPassed
+		if len(tests) == 0 && (tr.IsPassed || options.onlyPass != nil && *options.onlyPass) && !collapsed {
+			continue
+		}
 
-			for i, s := range test.Subtests {
-				isLast := i == len(test.Subtests)-1
-				processTest(s, true, isLast)
+		pkgRow := tablepkg.Row{buildPackageName(tr, collapsed, options), getStatusStr(packageStatus(tr), options.ReportColors())}
+		if options.durations {
+			pkgRow = append(pkgRow, FormatDuration(tr.ElapsedTime))
+		}
+		if hasLocation {
+			pkgRow = append(pkgRow, "")
+		}
+		if !options.ciMode && hasOutput {
+			var output string
+			if tr.Panic != nil {
+				output = formatPanic(tr.Panic, options.ReportColors())
 			}
+			pkgRow = append(pkgRow, output)
+		}
+		t.AppendRow(pkgRow)
+		t.AppendSeparator()
+		if collapsed {
+			continue
+		}
+
+		for _, test := range tests {
+			processTest(test, 0, "", false)
 			t.AppendSeparator()
 		}
 	}
@@ -93,50 +154,52 @@ func BuildTable(sum *parserpkg.Summary, opts ...RenderOptionFunc) tablepkg.Write
 	return t
 }
 
-// extractErrorOrPanic extracts error or panic from the given text
-func extractErrorOrPanic(text string) (string, error) {
-	re := regexp.MustCompile(`Error:(?s)(.*?)(\n\s*Test:)`)
-	matches := re.FindStringSubmatch(text)
-	if len(matches) > 1 {
-		errorText := matches[1]
-		errorText = strings.ReplaceAll(errorText, "\t", " ")
-		return strings.TrimSpace(errorText), nil
+// packageStatus returns the status of the given package
+func packageStatus(pkg *parserpkg.PackageResult) parserpkg.Status {
+	if pkg.IsPassed {
+		return parserpkg.StatusPass
 	}
+	return parserpkg.StatusFail
+}
 
-	rePanic := regexp.MustCompile(`panic:(?s)(.*?)(\n\sgoroutine)`)
-	matchesPanic := rePanic.FindStringSubmatch(text)
-	if len(matchesPanic) > 1 {
-		panicText := matchesPanic[1]
-		panicText = strings.ReplaceAll(panicText, "\t", " ")
-		return strings.TrimSpace(panicText), nil
-	}
+// buildPackageName returns the name cell of a package header row with the
+// elapsed time (unless it has its own column) and coverage of the package,
+// and the number of tests if the package is collapsed
+func buildPackageName(pkg *parserpkg.PackageResult, collapsed bool, options *renderOption) string {
+	name := formatWithColor(pkg.PackageName, getStatusColor(packageStatus(pkg)), options.ReportColors(), true)
 
-	reLog := regexp.MustCompile(`(?m)^\s*---\sLOG:(.*)$`)
-	matchesLog := reLog.FindStringSubmatch(text)
-	if len(matchesLog) > 1 {
-		logText := matchesLog[1]
-		logText = strings.ReplaceAll(logText, "\t", " ")
-		return strings.TrimSpace(logText), nil
+	var details []string
+	if pkg.BuildFailed {
+		details = append(details, formatWithColor("build failed", textpkg.FgRed, options.ReportColors(), false))
+	}
+	if !options.durations {
+		details = append(details, FormatDuration(pkg.ElapsedTime))
+	}
+	if pkg.Coverage != nil {
+		details = append(details, formatCoverage(*pkg.Coverage, options))
+	}
+	if collapsed {
+		details = append(details, fmt.Sprintf("%d tests", len(pkg.TestResults)))
 	}
 
-	reBuildFailed := regexp.MustCompile(`FAIL\s*[^\s]+ \[build failed\](.*)`)
-	matchesBuildFailed := reBuildFailed.FindStringSubmatch(text)
-	if len(matchesBuildFailed) > 0 {
-		buildFailedText := matchesBuildFailed[0]
-		buildFailedText = strings.ReplaceAll(buildFailedText, "\t", " ")
-		return strings.TrimSpace(buildFailedText), nil
+	if len(details) == 0 {
+		return name
 	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
 
-	return text, nil
+// FormatDuration formats the given elapsed time in seconds
+func FormatDuration(seconds float64) string {
+	return fmt.Sprintf("%.2fs", seconds)
 }
 
 // formatWithColor formats the given output with the given color
 func formatWithColor(output string, color textpkg.Color, applyColor bool, isBold bool) string {
 	if !applyColor {
 		if isBold {
-			return textpkg.Bold.Sprintf(color.Sprintf(output))
+			return textpkg.Bold.Sprint(color.Sprint(output))
 		}
-		return color.Sprintf(output)
+		return color.Sprint(output)
 	}
 
 	if isBold {
@@ -145,16 +208,28 @@ func formatWithColor(output string, color textpkg.Color, applyColor bool, isBold
 	return fmt.Sprintf("<span %s>%s</span>", color.HTMLProperty(), output)
 }
 
-// getIsPassedStr returns the string representation of the given isPassed value
-func getIsPassedStr(isPassed bool, reportColors bool) string {
-	var passedStr string
-	if isPassed {
-		passedStr = formatWithColor("✓ pass", textpkg.FgGreen, reportColors, true)
-	} else {
-		passedStr = formatWithColor("× fail", textpkg.FgRed, reportColors, true)
+// getStatusStr returns the string representation of the given status
+func getStatusStr(status parserpkg.Status, reportColors bool) string {
+	switch status {
+	case parserpkg.StatusFail:
+		return formatWithColor("× fail", getStatusColor(status), reportColors, true)
+	case parserpkg.StatusSkip:
+		return formatWithColor("⊘ skip", getStatusColor(status), reportColors, true)
+	default:
+		return formatWithColor("✓ pass", getStatusColor(status), reportColors, true)
 	}
+}
 
-	return passedStr
+// getStatusColor returns the color used to display the given status
+func getStatusColor(status parserpkg.Status) textpkg.Color {
+	switch status {
+	case parserpkg.StatusFail:
+		return textpkg.FgRed
+	case parserpkg.StatusSkip:
+		return textpkg.FgYellow
+	default:
+		return textpkg.FgGreen
+	}
 }
 
 // getSymbol returns the symbol for the given test
@@ -171,6 +246,18 @@ func getSymbol(isLast bool, reportColors bool) string {
 	return symbol
 }
 
+// getIndent returns the prefix continuing the tree below the given test for its subtests
+func getIndent(isLast bool, reportColors bool) string {
+	if reportColors {
+		return "&nbsp;&nbsp;&nbsp;&nbsp;"
+	}
+
+	if isLast {
+		return "    "
+	}
+	return " │  "
+}
+
 // formatCompactOutput formats the given string to a compact output
 func formatCompactOutput(s string) string {
 	s = strings.TrimSpace(s)
@@ -180,25 +267,61 @@ func formatCompactOutput(s string) string {
 	return s
 }
 
-// getOutput returns the output of the given test
-func getOutput(test *parserpkg.TestResult) string {
-	output, err := extractErrorOrPanic(strings.TrimSpace(strings.Join(test.Output, "\n")))
-	if err != nil {
+// getLocation returns the file:line locations of the failures of the given test
+func getLocation(test *parserpkg.TestResult) string {
+	if test.Status != parserpkg.StatusFail {
 		return ""
 	}
 
-	if !test.IsPassed {
-		return formatCompactOutput(output)
+	var locations []string
+	seen := make(map[string]bool)
+	for _, f := range test.Failures {
+		if f.File == "" {
+			continue
+		}
+		location := fmt.Sprintf("%s:%d", filepath.Base(f.File), f.Line)
+		if !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+
+	// fall back to the location found in the output, e.g. by a user-defined extraction rule
+	if len(locations) == 0 {
+		if r := extract.Extract(strings.Join(test.Output, "\n")); r.File != "" {
+			locations = append(locations, fmt.Sprintf("%s:%d", filepath.Base(r.File), r.Line))
+		}
+	}
+	return strings.Join(locations, "\n")
+}
+
+// getOutput returns the output of the given test
+func getOutput(test *parserpkg.TestResult, reportColors bool) string {
+	if test.Status == parserpkg.StatusFail && test.Panic != nil {
+		return formatPanic(test.Panic, reportColors)
+	}
+	if test.Status == parserpkg.StatusFail && hasComparison(test.Failures) {
+		return formatComparisons(test.Failures, reportColors)
+	}
+
+	switch test.Status {
+	case parserpkg.StatusFail:
+		return formatCompactOutput(extract.Extract(strings.Join(test.Output, "\n")).Message)
+	case parserpkg.StatusSkip:
+		return test.SkipReason
 	}
 
 	return ""
 }
 
 type renderOption struct {
-	onlyFail     *bool
-	onlyPass     *bool
-	reportColors *bool
-	ciMode       bool
+	onlyFail       *bool
+	onlyPass       *bool
+	reportColors   *bool
+	ciMode         bool
+	minCoverage    float64
+	collapsePassed bool
+	durations      bool
 }
 
 func (r renderOption) ReportColors() bool {
@@ -236,3 +359,24 @@ func WithOnlyPass() RenderOptionFunc {
 		opt.onlyPass = &b
 	}
 }
+
+// WithMinCoverage highlights the coverage below the given percentage
+func WithMinCoverage(minCoverage float64) RenderOptionFunc {
+	return func(opt *renderOption) {
+		opt.minCoverage = minCoverage
+	}
+}
+
+// WithCollapsePassed renders every passed package as a single row without its tests
+func WithCollapsePassed() RenderOptionFunc {
+	return func(opt *renderOption) {
+		opt.collapsePassed = true
+	}
+}
+
+// WithDurations adds a column with the elapsed time of every package and test
+func WithDurations() RenderOptionFunc {
+	return func(opt *renderOption) {
+		opt.durations = true
+	}
+}